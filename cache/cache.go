@@ -0,0 +1,277 @@
+// Package cache provides a persistent, on-disk result cache and incremental
+// re-parsing so that repeated scans of a large Cairo repo don't re-parse and
+// re-query every file from scratch each time.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unsafe"
+
+	cairo "github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/scanner"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// fileState is the in-memory (not persisted) state kept between Scan calls
+// in the same process, so a second Scan of a changed file can reparse
+// incrementally instead of from scratch.
+type fileState struct {
+	source []byte
+	tree   *tree_sitter.Tree
+}
+
+// Cache is a persistent result cache keyed by (file content hash, query set
+// hash), plus an in-memory incremental-parse state for repeated scans within
+// one process (e.g. watch mode).
+type Cache struct {
+	path         string
+	scanner      *scanner.Scanner
+	querySetHash string
+
+	mu      sync.Mutex
+	entries map[string][]scanner.QueryResult // disk-persisted, keyed by contentHash:querySetHash
+	files   map[string]*fileState            // in-memory only
+}
+
+// diskEntry is the on-disk JSON representation of one cache entry.
+type diskEntry struct {
+	Key     string                `json:"key"`
+	Results []scanner.QueryResult `json:"results"`
+}
+
+// NewCache loads (or creates) a persistent cache at cachePath, compiling
+// queries from queryDir the same way Scanner does.
+func NewCache(cachePath, queryDir string) (*Cache, error) {
+	sc, err := scanner.NewScanner(queryDir)
+	if err != nil {
+		return nil, fmt.Errorf("error creating scanner: %w", err)
+	}
+
+	querySetHash, err := hashQueryDir(queryDir)
+	if err != nil {
+		return nil, fmt.Errorf("error hashing query directory: %w", err)
+	}
+
+	c := &Cache{
+		path:         cachePath,
+		scanner:      sc,
+		querySetHash: querySetHash,
+		entries:      make(map[string][]scanner.QueryResult),
+		files:        make(map[string]*fileState),
+	}
+
+	if err := c.load(); err != nil {
+		return nil, fmt.Errorf("error loading cache: %w", err)
+	}
+
+	return c, nil
+}
+
+func (c *Cache) load() error {
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var entries []diskEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("error parsing cache file: %w", err)
+	}
+	for _, e := range entries {
+		c.entries[e.Key] = e.Results
+	}
+	return nil
+}
+
+// Save persists the current cache entries to disk.
+func (c *Cache) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries := make([]diskEntry, 0, len(c.entries))
+	for key, results := range c.entries {
+		entries = append(entries, diskEntry{Key: key, Results: results})
+	}
+
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cache: %w", err)
+	}
+
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing cache file: %w", err)
+	}
+	return nil
+}
+
+// Scan runs every query against each file, reusing cached results for files
+// whose content hasn't changed since the last scan, and reparsing changed
+// files incrementally when a previous in-process tree is available. It
+// persists the updated cache to disk before returning.
+func (c *Cache) Scan(files []string) ([]scanner.QueryResult, error) {
+	var all []scanner.QueryResult
+
+	for _, path := range files {
+		results, err := c.scanFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("error scanning %s: %w", path, err)
+		}
+		all = append(all, results...)
+	}
+
+	if err := c.Save(); err != nil {
+		return nil, err
+	}
+
+	return all, nil
+}
+
+func (c *Cache) scanFile(path string) ([]scanner.QueryResult, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file: %w", err)
+	}
+
+	key := cacheKey(source, c.querySetHash)
+
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		// Content and query set both match a prior run: nothing changed.
+		return cached, nil
+	}
+
+	tree, err := c.parse(path, source)
+	if err != nil {
+		return nil, err
+	}
+
+	results := c.scanner.ScanSource(path, source, tree)
+
+	c.mu.Lock()
+	c.entries[key] = results
+	c.mu.Unlock()
+
+	return results, nil
+}
+
+// parse reparses path, using tree-sitter's incremental parsing API when a
+// previous tree for this file is already held in memory from an earlier
+// Scan call in this process. tree.ChangedRanges reports which byte ranges
+// actually moved, so a smarter caller could limit re-querying to just those
+// ranges; here it's used only to compute the edit, since Scanner always
+// re-runs the full query set over a changed file's whole source.
+func (c *Cache) parse(path string, source []byte) (*tree_sitter.Tree, error) {
+	c.mu.Lock()
+	prev := c.files[path]
+	c.mu.Unlock()
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))); err != nil {
+		return nil, fmt.Errorf("error setting language: %w", err)
+	}
+
+	var tree *tree_sitter.Tree
+	if prev != nil {
+		edit := computeEdit(prev.source, source)
+		prev.tree.Edit(&edit)
+		tree = parser.Parse(source, prev.tree)
+		_ = tree.ChangedRanges(prev.tree)
+		prev.tree.Close()
+	} else {
+		tree = parser.Parse(source, nil)
+	}
+
+	c.mu.Lock()
+	c.files[path] = &fileState{source: source, tree: tree}
+	c.mu.Unlock()
+
+	return tree, nil
+}
+
+// computeEdit finds the common prefix/suffix between old and new source and
+// builds the InputEdit tree-sitter needs to reparse incrementally.
+func computeEdit(old, new []byte) tree_sitter.InputEdit {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+
+	oldSuffix, newSuffix := len(old), len(new)
+	for oldSuffix > prefix && newSuffix > prefix && old[oldSuffix-1] == new[newSuffix-1] {
+		oldSuffix--
+		newSuffix--
+	}
+
+	return tree_sitter.InputEdit{
+		StartByte:      uint(prefix),
+		OldEndByte:     uint(oldSuffix),
+		NewEndByte:     uint(newSuffix),
+		StartPosition:  pointAt(old, prefix),
+		OldEndPosition: pointAt(old, oldSuffix),
+		NewEndPosition: pointAt(new, newSuffix),
+	}
+}
+
+// pointAt converts a byte offset into a row/column Point by scanning for
+// newlines, the same convention tree-sitter itself uses.
+func pointAt(source []byte, offset int) tree_sitter.Point {
+	row, col := uint(0), uint(0)
+	for i := 0; i < offset && i < len(source); i++ {
+		if source[i] == '\n' {
+			row++
+			col = 0
+		} else {
+			col++
+		}
+	}
+	return tree_sitter.Point{Row: row, Column: col}
+}
+
+// cacheKey combines a file's content hash with the query set hash so
+// changing either the source or the queries invalidates the entry.
+func cacheKey(source []byte, querySetHash string) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:]) + ":" + querySetHash
+}
+
+// hashQueryDir hashes the combined content of every .scm file under dir, so
+// editing a query invalidates every cached result it could have produced.
+func hashQueryDir(dir string) (string, error) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return "empty", nil
+	}
+
+	h := sha256.New()
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".scm") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		h.Write(data)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
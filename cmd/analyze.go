@@ -2,81 +2,259 @@ package cmd
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
-	"unsafe"
+	"path/filepath"
 
-	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/pkg/baseline"
+	"github.com/jeffrydegrande/solidair/pkg/config"
+	"github.com/jeffrydegrande/solidair/pkg/report"
 	"github.com/spf13/cobra"
-	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
+// analyzeFormatFlag is the --format flag value for analyzeCmd: text, json, or sarif.
+var analyzeFormatFlag string
+
+// analyzeWorkersFlag is the number of goroutines used to parse a project's
+// files in parallel when [file] is a directory.
+var analyzeWorkersFlag int
+
+// analyzeBaselineFlag is the --baseline flag value for analyzeCmd: a path to
+// a baseline.Baseline file whose entries are dropped from the report.
+var analyzeBaselineFlag string
+
+// analyzeUpdateBaselineFlag is --update-baseline: rewrite analyzeBaselineFlag
+// (or baseline.DefaultFilename if that's empty) from this run's findings
+// instead of filtering against it.
+var analyzeUpdateBaselineFlag bool
+
+// fileQueryResult pairs a QueryResult with the file it was found in, since
+// analyzing a directory spans more than one file.
+type fileQueryResult struct {
+	FilePath string
+	Result   QueryResult
+}
+
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze [file]",
-	Short: "Analyze a Cairo file for security vulnerabilities",
+	Use:   "analyze [file|dir]",
+	Short: "Analyze a Cairo or Solidity file or project for security vulnerabilities",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		filename := args[0]
+		path := args[0]
 		queryDir, _ := cmd.Flags().GetString("query-dir")
 
-		// Read the source code
-		data, err := os.ReadFile(filename)
+		cfg, err := loadConfig(cmd)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", filename, err)
+			fmt.Fprintln(os.Stderr, err)
 			os.Exit(1)
 		}
 
-		// Parse the source code
-		parser := tree_sitter.NewParser()
-		defer parser.Close()
-
-		err = parser.SetLanguage(tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language())))
+		files, err := discoverFiles(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error setting language: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error discovering %s: %v\n", path, err)
 			os.Exit(1)
 		}
-		tree := parser.Parse(data, nil)
-		defer tree.Close()
+		files = filterFiles(files, cfg)
 
-		// Read all query files
-		queries, err := ReadQueryFiles(queryDir)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading query files: %v\n", err)
-			os.Exit(1)
-		}
-
-		if len(queries) == 0 {
-			fmt.Printf("No query files found in %s\n", queryDir)
+		if len(files) == 0 {
+			fmt.Printf("No files with a registered language backend found in %s\n", path)
 			os.Exit(0)
 		}
 
-		fmt.Printf("Loaded %d queries from %s\n", len(queries), queryDir)
+		scanner := NewScanner(queryDir, cfg, analyzeWorkersFlag)
+		resultsCh, errsCh := scanner.ScanFiles(cmd.Context(), files)
 
-		// Run all queries against the source code
-		results, err := RunQueries(data, tree, queries)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error running queries: %v\n", err)
+		var all []fileQueryResult
+		scanFailed := false
+		for resultsCh != nil || errsCh != nil {
+			select {
+			case r, ok := <-resultsCh:
+				if !ok {
+					resultsCh = nil
+					continue
+				}
+				all = append(all, r)
+			case err, ok := <-errsCh:
+				if !ok {
+					errsCh = nil
+					continue
+				}
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					scanFailed = true
+				}
+			}
+		}
+
+		if cmd.Context().Err() != nil {
+			fmt.Fprintln(os.Stderr, cmd.Context().Err())
 			os.Exit(1)
 		}
 
-		// Print the results
-		if len(results) == 0 {
-			fmt.Println("No vulnerabilities found.")
-		} else {
-			fmt.Printf("Found %d potential vulnerabilities:\n\n", len(results))
-
-			for i, result := range results {
-				fmt.Printf("Vulnerability #%d: %s\n", i+1, result.QueryName)
-				fmt.Printf("  Source: %s\n", result.QueryFile)
-				if result.Description != "" {
-					fmt.Printf("  Description: %s\n", result.Description)
+		if analyzeUpdateBaselineFlag {
+			path := analyzeBaselineFlag
+			if path == "" {
+				path = baseline.DefaultFilename
+			}
+			if err := baseline.Save(path, baselineEntries(all)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			fmt.Printf("Updated baseline %s with %d finding(s)\n", path, len(all))
+		} else if analyzeBaselineFlag != "" {
+			bl, err := baseline.Load(analyzeBaselineFlag)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			all = filterBaselined(all, bl)
+		}
+
+		switch analyzeFormatFlag {
+		case "sarif":
+			sarifData, err := report.SARIF(queryResultsToFindings(all))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error rendering SARIF: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(sarifData))
+		case "json":
+			if err := report.JSONLines(os.Stdout, queryResultsToFindings(all)); err != nil {
+				fmt.Fprintf(os.Stderr, "Error rendering JSON: %v\n", err)
+				os.Exit(1)
+			}
+		case "text", "":
+			if len(all) == 0 {
+				fmt.Println("No vulnerabilities found.")
+			} else {
+				fmt.Printf("Found %d potential vulnerabilities:\n\n", len(all))
+
+				for i, fr := range all {
+					fmt.Printf("Vulnerability #%d: %s\n", i+1, fr.Result.QueryName)
+					fmt.Printf("  File: %s\n", fr.FilePath)
+					fmt.Printf("  Source: %s\n", fr.Result.QueryFile)
+					if fr.Result.Description != "" {
+						fmt.Printf("  Description: %s\n", fr.Result.Description)
+					}
+					if fr.Result.Severity != "" {
+						fmt.Printf("  Severity: %s\n", fr.Result.Severity)
+					}
+					fmt.Printf("  Line: %d\n", fr.Result.LineNumber)
+					fmt.Printf("  Code: %s\n\n", fr.Result.Code)
 				}
-				fmt.Printf("  Line: %d\n", result.LineNumber)
-				fmt.Printf("  Code: %s\n\n", result.Code)
 			}
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown format %q (want text, json, or sarif)\n", analyzeFormatFlag)
+			os.Exit(1)
+		}
+
+		if scanFailed {
+			os.Exit(1)
 		}
 	},
 }
 
+// discoverFiles resolves path to the list of files analyzeCmd scans: path
+// itself if it's a single file, or every file under it with a registered
+// languages.Backend if it's a directory. Unlike project.DiscoverFiles (which
+// only ever walks .cairo files, for the Cairo-specific matchCmd), this walks
+// every language analyzeCmd knows how to parse, so a directory mixing Cairo
+// and Solidity scans in one pass.
+func discoverFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() {
+			if _, ok := languages.ForPath(p); ok {
+				files = append(files, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// filterFiles drops any file cfg excludes via blacklisted_paths,
+// exclude_paths, or blacklisted_extensions, so a directory walk over a
+// project with vendored or generated Cairo code only analyzes what cfg
+// considers the project's own.
+func filterFiles(files []string, cfg *config.Config) []string {
+	kept := files[:0]
+	for _, f := range files {
+		if !cfg.SkipPath(f) {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// baselineEntries builds the baseline.Entry list saved by --update-baseline,
+// one per current finding.
+func baselineEntries(all []fileQueryResult) []baseline.Entry {
+	entries := make([]baseline.Entry, len(all))
+	for i, fr := range all {
+		entries[i] = baseline.Entry{
+			QueryName:   fr.Result.QueryName,
+			FilePath:    fr.FilePath,
+			Fingerprint: baseline.Fingerprint(fr.Result.QueryName, fr.FilePath, fr.Result.Code, fr.Result.EnclosingConstruct),
+		}
+	}
+	return entries
+}
+
+// filterBaselined drops every result already accepted into bl, identified by
+// baseline.Fingerprint rather than file/line so edits elsewhere in the file
+// don't resurrect an already-baselined finding.
+func filterBaselined(all []fileQueryResult, bl *baseline.Baseline) []fileQueryResult {
+	kept := all[:0]
+	for _, fr := range all {
+		fp := baseline.Fingerprint(fr.Result.QueryName, fr.FilePath, fr.Result.Code, fr.Result.EnclosingConstruct)
+		if !bl.Contains(fp) {
+			kept = append(kept, fr)
+		}
+	}
+	return kept
+}
+
+// queryResultsToFindings adapts RunQueries' results to report.Finding,
+// using each result's own file path.
+func queryResultsToFindings(results []fileQueryResult) []report.Finding {
+	findings := make([]report.Finding, len(results))
+	for i, fr := range results {
+		findings[i] = report.Finding{
+			RuleID:      fr.Result.QueryName,
+			FilePath:    fr.FilePath,
+			Description: fr.Result.Description,
+			LineNumber:  fr.Result.LineNumber,
+			EndLine:     fr.Result.EndLine,
+			Column:      fr.Result.Column,
+			StartByte:   fr.Result.StartByte,
+			EndByte:     fr.Result.EndByte,
+			Code:        fr.Result.Code,
+			Severity:    fr.Result.Severity,
+		}
+	}
+	return findings
+}
+
 func init() {
+	analyzeCmd.Flags().StringVar(&analyzeFormatFlag, "format", "text", "output format: text, json, or sarif")
+	analyzeCmd.Flags().IntVar(&analyzeWorkersFlag, "workers", 4, "number of files to parse in parallel when analyzing a directory")
+	analyzeCmd.Flags().StringVar(&analyzeBaselineFlag, "baseline", "", "path to a baseline file; findings already recorded there are dropped from the report")
+	analyzeCmd.Flags().BoolVar(&analyzeUpdateBaselineFlag, "update-baseline", false, "rewrite --baseline (or "+baseline.DefaultFilename+") from this run's findings instead of filtering against it")
 	rootCmd.AddCommand(analyzeCmd)
-}
\ No newline at end of file
+}
@@ -5,11 +5,10 @@ import (
 	"fmt"
 	"os"
 
-	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/languages"
 	"github.com/jeffrydegrande/solidair/pkg/concepts"
-	"github.com/jeffrydegrande/solidair/pkg/embedding"
-	"github.com/jeffrydegrande/solidair/pkg/templates"
-	"github.com/jeffrydegrande/solidair/pkg/variables"
+	"github.com/jeffrydegrande/solidair/variables"
 	"github.com/spf13/cobra"
 )
 
@@ -33,6 +32,12 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 	apiKey, _ := cmd.Flags().GetString("api-key")
 	offline, _ := cmd.Flags().GetBool("offline")
 
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
 	// Load security concepts
 	securityConcepts, err := concepts.LoadSecurityConcepts()
 	if err != nil {
@@ -40,6 +45,12 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	backend, ok := languages.ForExtension(".cairo")
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Error: no cairo language backend registered")
+		os.Exit(1)
+	}
+
 	// Read the source code
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -48,7 +59,7 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 	}
 
 	// Parse the source code
-	tree, err := cairo.Parse(data)
+	tree, err := backend.Parse(data)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
 		os.Exit(1)
@@ -56,7 +67,7 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 	defer tree.Close()
 
 	// Extract variables
-	vars, err := variables.ExtractVariables(data, tree)
+	vars, err := variables.ExtractVariables(data, tree, backend)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error extracting variables: %v\n", err)
 		os.Exit(1)
@@ -66,7 +77,7 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 	var matcher *embedding.EmbeddingMatcher
 	if offline {
 		// Offline mode
-		matcher = embedding.NewEmbeddingMatcher(nil, securityConcepts, true)
+		matcher = embedding.NewEmbeddingMatcher(nil, securityConcepts, true, nil)
 	} else {
 		// Online mode with OpenAI API
 		if apiKey == "" {
@@ -77,7 +88,7 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 			}
 		}
 		openAIClient := embedding.NewOpenAIClient(apiKey)
-		matcher = embedding.NewEmbeddingMatcher(openAIClient, securityConcepts, false)
+		matcher = embedding.NewEmbeddingMatcher(embedding.NewOpenAIProvider(openAIClient), securityConcepts, false, nil)
 	}
 
 	// Match variables to concepts
@@ -89,7 +100,7 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 	}
 
 	// Read regular and templated queries
-	queries, err := ReadQueryFiles(queryDir)
+	queries, err := ReadQueryFiles(queryDir, backend, cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error reading query files: %v\n", err)
 		os.Exit(1)
@@ -103,9 +114,9 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 	fmt.Printf("Loaded %d queries\n", len(queries))
 
 	// Parse templates
-	queryTemplates := make(map[string]*templates.QueryTemplate)
+	queryTemplates := make(map[string]*QueryTemplate)
 	for source, content := range queries {
-		template, err := templates.ParseQueryTemplate(content, source)
+		template, err := ParseQueryTemplate(content, source)
 		if err != nil {
 			fmt.Printf("Warning: Error parsing query template %s: %v\n", source, err)
 			continue
@@ -113,11 +124,14 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 		queryTemplates[source] = template
 	}
 
-	// Process templated queries
-	parameterizedQueries := templates.ProcessTemplatedQueries(queryTemplates, conceptMatches)
+	// Process templated queries, picking the top-K joint assignment per
+	// template (hybrid-scored) rather than just each concept's greedy best
+	// match, so a template's parameters are resolved together.
+	hybrid := DefaultHybridMatchConfig()
+	parameterizedQueries := ProcessTemplatedQueriesJoint(queryTemplates, conceptMatches, &hybrid, 0, 0)
 
 	// Run standard queries (non-templated)
-	standardResults, err := RunQueries(data, tree, queries)
+	standardResults, err := RunQueries(data, tree, backend, queries, cfg)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error running standard queries: %v\n", err)
 		os.Exit(1)
@@ -132,7 +146,7 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 		}
 
 		// Run the parameterized query
-		results, err := RunQueries(data, tree, queryMap)
+		results, err := RunQueries(data, tree, backend, queryMap, cfg)
 		if err != nil {
 			fmt.Printf("Warning: Error running parameterized query %s: %v\n",
 				paramQuery.Template.Name, err)
@@ -166,4 +180,4 @@ func analyzeSmartMain(cmd *cobra.Command, args []string) {
 			fmt.Printf("  Code: %s\n\n", result.Code)
 		}
 	}
-}
\ No newline at end of file
+}
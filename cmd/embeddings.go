@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"sort"
 
+	"github.com/jeffrydegrande/solidair/types"
 	"github.com/pelletier/go-toml/v2"
 	"github.com/sashabaranov/go-openai"
 )
@@ -15,6 +16,12 @@ import (
 // Use os.ReadFile instead of embed for flexibility
 // We'll read from the absolute path
 
+// VariableInfo is this package's name for the same extracted-variable shape
+// the rest of the tree already settled on as types.VariableInfo, kept as an
+// alias so this file's matcher (predating that package) didn't need every
+// signature below rewritten to spell it out.
+type VariableInfo = types.VariableInfo
+
 // Embedding represents a vector embedding for a variable or concept
 type Embedding struct {
 	Vector []float32 `toml:"vector"` // The embedding vector
@@ -33,6 +40,12 @@ type ConceptMatch struct {
 	Variable        VariableInfo // The matched variable
 	Concept         string       // The security concept (e.g., "active", "initialized")
 	SimilarityScore float32      // 0.0-1.0 score of the match quality
+	// SemanticScore and LexicalScore are the unfused cosine and lexical
+	// sub-scores matchVariableHybrid computed SimilarityScore from, so a
+	// caller can explain why a hybrid match ranked where it did. Both are
+	// zero for matches produced by the pure-cosine path in MatchVariable.
+	SemanticScore float32
+	LexicalScore  float32
 }
 
 // EmbeddingCache provides caching for computed embeddings
@@ -40,6 +53,23 @@ type EmbeddingCache struct {
 	Variables map[string]Embedding // Cache of variable embeddings
 }
 
+// EmbeddingsProvider computes vector embeddings for a batch of texts. It's
+// the seam EmbeddingMatcher goes through to get a variable's embedding, so
+// OpenAI can be swapped for a self-hosted or offline backend without
+// touching any matching logic.
+type EmbeddingsProvider interface {
+	// Embed computes one embedding per text, in order.
+	Embed(ctx context.Context, texts []string) ([]Embedding, error)
+	// Dimensions is the length of the vectors this provider produces.
+	Dimensions() int
+	// MaxTokens is the largest single input this provider accepts.
+	MaxTokens() int
+	// Name identifies the provider and model, stamped onto embeddingEntry
+	// so LoadSecurityConcepts can tell a cached vector from a different
+	// provider apart from one the active provider would produce.
+	Name() string
+}
+
 // OpenAIClient represents a client for the OpenAI API
 type OpenAIClient struct {
 	Client *openai.Client
@@ -52,30 +82,52 @@ func NewOpenAIClient(apiKey string) *OpenAIClient {
 	}
 }
 
-// GetEmbedding calculates an embedding for the given text using OpenAI's API
-func (c *OpenAIClient) GetEmbedding(ctx context.Context, text string) (Embedding, error) {
+// Embed implements EmbeddingsProvider using OpenAI's ada-002 embedding model.
+func (c *OpenAIClient) Embed(ctx context.Context, texts []string) ([]Embedding, error) {
 	resp, err := c.Client.CreateEmbeddings(
 		ctx,
 		openai.EmbeddingRequest{
-			Input: []string{text},
+			Input: texts,
 			Model: openai.AdaEmbeddingV2,
 		},
 	)
 	if err != nil {
-		return Embedding{}, fmt.Errorf("error getting embedding: %w", err)
+		return nil, fmt.Errorf("error getting embeddings: %w", err)
 	}
 
-	if len(resp.Data) == 0 {
-		return Embedding{}, fmt.Errorf("no embedding data returned")
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
 	}
 
-	// Convert from []float64 to []float32 to save memory
-	vector := make([]float32, len(resp.Data[0].Embedding))
-	for i, v := range resp.Data[0].Embedding {
-		vector[i] = float32(v)
+	result := make([]Embedding, len(texts))
+	for i, d := range resp.Data {
+		// Convert from []float64 to []float32 to save memory
+		vector := make([]float32, len(d.Embedding))
+		for j, v := range d.Embedding {
+			vector[j] = float32(v)
+		}
+		result[i] = Embedding{Vector: vector}
 	}
 
-	return Embedding{Vector: vector}, nil
+	return result, nil
+}
+
+// Dimensions implements EmbeddingsProvider. Ada-002 always returns 1536-dim vectors.
+func (c *OpenAIClient) Dimensions() int { return 1536 }
+
+// MaxTokens implements EmbeddingsProvider with ada-002's per-request token limit.
+func (c *OpenAIClient) MaxTokens() int { return 8191 }
+
+// Name implements EmbeddingsProvider.
+func (c *OpenAIClient) Name() string { return "openai-" + string(openai.AdaEmbeddingV2) }
+
+// GetEmbedding calculates an embedding for a single text using OpenAI's API.
+func (c *OpenAIClient) GetEmbedding(ctx context.Context, text string) (Embedding, error) {
+	embeddings, err := c.Embed(ctx, []string{text})
+	if err != nil {
+		return Embedding{}, err
+	}
+	return embeddings[0], nil
 }
 
 // CosineSimilarity calculates the cosine similarity between two embeddings
@@ -112,21 +164,34 @@ func CosineSimilarity(a, b Embedding) float32 {
 
 // EmbeddingMatcher is a system for matching variables to security concepts
 type EmbeddingMatcher struct {
-	OpenAI              *OpenAIClient
+	Provider            EmbeddingsProvider
 	Concepts            []SecurityConcept
 	Cache               *EmbeddingCache
 	SimilarityThreshold float32
-	Offline             bool
+	// DiskCache, when set, backs Cache with a persistent on-disk store so
+	// embeddings survive between runs; nil leaves the matcher re-embedding
+	// every variable it hasn't already seen this process.
+	DiskCache *DiskEmbeddingCache
+	// Hybrid, when set, switches MatchVariable from pure cosine similarity
+	// to a hybrid score that blends cosine with a BM25 + synonym lexical
+	// score as a weighted average (see HybridMatchConfig). This lets templates resolve even
+	// offline, or when a variable name has strong string/synonym overlap
+	// with a concept but little embedding-space overlap.
+	Hybrid *HybridMatchConfig
 }
 
-// NewEmbeddingMatcher creates a new matcher with the provided OpenAI client and concepts
-func NewEmbeddingMatcher(client *OpenAIClient, concepts []SecurityConcept, offline bool) *EmbeddingMatcher {
+// NewEmbeddingMatcher creates a new matcher backed by provider. If offline is
+// true and provider is nil, it defaults to the deterministic local hash
+// provider rather than requiring a network-backed one.
+func NewEmbeddingMatcher(provider EmbeddingsProvider, concepts []SecurityConcept, offline bool) *EmbeddingMatcher {
+	if offline && provider == nil {
+		provider = NewLocalHashEmbeddingsProvider()
+	}
 	return &EmbeddingMatcher{
-		OpenAI:              client,
+		Provider:            provider,
 		Concepts:            concepts,
 		Cache:               &EmbeddingCache{Variables: make(map[string]Embedding)},
 		SimilarityThreshold: 0.7, // Default threshold
-		Offline:             offline,
 	}
 }
 
@@ -137,45 +202,35 @@ func (m *EmbeddingMatcher) GetVariableEmbedding(ctx context.Context, variable Va
 		return embedding, nil
 	}
 
-	// If we're in offline mode, use a simple fallback method
-	if m.Offline {
-		return m.getOfflineEmbedding(variable.Name), nil
+	if m.DiskCache != nil {
+		if embedding, ok, err := m.DiskCache.Get(m.Provider.Name(), variable.Name); err == nil && ok {
+			m.Cache.Variables[variable.Name] = embedding
+			return embedding, nil
+		}
 	}
 
-	// Get embedding from OpenAI
-	embedding, err := m.OpenAI.GetEmbedding(ctx, variable.Name)
+	embeddings, err := m.Provider.Embed(ctx, []string{variable.Name})
 	if err != nil {
 		return Embedding{}, err
 	}
 
 	// Cache the embedding
-	m.Cache.Variables[variable.Name] = embedding
-
-	return embedding, nil
-}
-
-// getOfflineEmbedding creates a simple embedding for offline mode
-// This is a placeholder - in a real implementation, we'd use a more
-// sophisticated method for generating offline embeddings
-func (m *EmbeddingMatcher) getOfflineEmbedding(name string) Embedding {
-	// Create a simple embedding based on string characteristics
-	// This is just a placeholder that creates a vector with a few dimensions
-	vector := make([]float32, 3)
-
-	// Fill with some values based on the string
-	for i := 0; i < len(vector); i++ {
-		if i < len(name) {
-			vector[i] = float32(name[i%len(name)]) / 255.0
-		} else {
-			vector[i] = 0
-		}
+	m.Cache.Variables[variable.Name] = embeddings[0]
+	if m.DiskCache != nil {
+		m.DiskCache.Put(m.Provider.Name(), variable.Name, embeddings[0])
 	}
 
-	return Embedding{Vector: vector}
+	return embeddings[0], nil
 }
 
-// MatchVariable finds the best matching security concept for a variable
+// MatchVariable finds the best matching security concept for a variable. If
+// m.Hybrid is set, ranking is delegated to matchVariableHybrid instead of
+// pure cosine similarity.
 func (m *EmbeddingMatcher) MatchVariable(ctx context.Context, variable VariableInfo) ([]ConceptMatch, error) {
+	if m.Hybrid != nil {
+		return m.matchVariableHybrid(ctx, variable)
+	}
+
 	// Get embedding for the variable
 	varEmbedding, err := m.GetVariableEmbedding(ctx, variable)
 	if err != nil {
@@ -208,6 +263,10 @@ func (m *EmbeddingMatcher) MatchVariable(ctx context.Context, variable VariableI
 
 // MatchVariables matches multiple variables to security concepts
 func (m *EmbeddingMatcher) MatchVariables(ctx context.Context, variables []VariableInfo) (map[string][]ConceptMatch, error) {
+	if err := m.warmCache(ctx, variables); err != nil {
+		return nil, err
+	}
+
 	result := make(map[string][]ConceptMatch)
 
 	for _, variable := range variables {
@@ -225,8 +284,56 @@ func (m *EmbeddingMatcher) MatchVariables(ctx context.Context, variables []Varia
 	return result, nil
 }
 
-// LoadSecurityConcepts loads the pre-computed security concept embeddings
-func LoadSecurityConcepts() ([]SecurityConcept, error) {
+// warmCache batches embedding requests for every variable not already
+// cached through an embeddingQueue, so MatchVariables issues a handful of
+// provider calls instead of one per variable.
+func (m *EmbeddingMatcher) warmCache(ctx context.Context, variables []VariableInfo) error {
+	type pendingVar struct {
+		name   string
+		future <-chan queuedResult
+	}
+
+	queue := newEmbeddingQueue(m.Provider)
+	var pending []pendingVar
+
+	for _, variable := range variables {
+		if _, ok := m.Cache.Variables[variable.Name]; ok {
+			continue
+		}
+		if m.DiskCache != nil {
+			if embedding, ok, err := m.DiskCache.Get(m.Provider.Name(), variable.Name); err == nil && ok {
+				m.Cache.Variables[variable.Name] = embedding
+				continue
+			}
+		}
+		pending = append(pending, pendingVar{name: variable.Name, future: queue.enqueue(variable.Name)})
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	queue.flush(ctx)
+
+	for _, p := range pending {
+		res := <-p.future
+		if res.err != nil {
+			return res.err
+		}
+		m.Cache.Variables[p.name] = res.embedding
+		if m.DiskCache != nil {
+			m.DiskCache.Put(m.Provider.Name(), p.name, res.embedding)
+		}
+	}
+
+	return nil
+}
+
+// LoadSecurityConcepts loads the pre-computed security concept embeddings.
+// A cached entry whose EmbedderName or Dimensions doesn't match provider is
+// dropped rather than merged, so CosineSimilarity is never asked to compare
+// vectors from two different embedding spaces; provider may be nil to skip
+// this check (e.g. when the caller only wants concept metadata).
+func LoadSecurityConcepts(provider EmbeddingsProvider) ([]SecurityConcept, error) {
 	// Try different possible locations for the embeddings directory
 	embedDirs := []string{
 		"embeddings", // Current directory
@@ -322,13 +429,25 @@ func LoadSecurityConcepts() ([]SecurityConcept, error) {
 		return conceptsConfig.Concepts, nil
 	}
 
-	// Merge embeddings into concepts
+	// Merge embeddings into concepts, skipping any stamped with a different
+	// provider or dimension than the one we're about to match with.
 	for _, entry := range embeddingsConfig.Embeddings {
-		if concept, exists := conceptsMap[entry.ConceptName]; exists {
-			concept.Embedding = entry.Embedding
-		} else {
+		concept, exists := conceptsMap[entry.ConceptName]
+		if !exists {
 			fmt.Printf("Warning: Found embedding for unknown concept '%s'\n", entry.ConceptName)
+			continue
+		}
+
+		if provider != nil && entry.EmbedderName != "" && entry.EmbedderName != provider.Name() {
+			fmt.Printf("Warning: Skipping cached embedding for '%s' from provider '%s'; active provider is '%s'\n", entry.ConceptName, entry.EmbedderName, provider.Name())
+			continue
 		}
+		if provider != nil && entry.Dimensions != 0 && entry.Dimensions != provider.Dimensions() {
+			fmt.Printf("Warning: Skipping cached embedding for '%s' with dimension %d; active provider produces %d\n", entry.ConceptName, entry.Dimensions, provider.Dimensions())
+			continue
+		}
+
+		concept.Embedding = entry.Embedding
 	}
 
 	return conceptsConfig.Concepts, nil
@@ -406,21 +525,26 @@ func loadLegacyMetadataFormat(embeddingsDir string) ([]SecurityConcept, error) {
 	return config.Concepts, nil
 }
 
-// GenerateSecurityConceptsEmbeddings generates embeddings for security concepts
-func GenerateSecurityConceptsEmbeddings(ctx context.Context, client *OpenAIClient, concepts []SecurityConcept) ([]SecurityConcept, error) {
+// GenerateSecurityConceptsEmbeddings generates embeddings for security
+// concepts, batching requests to provider through an embeddingQueue instead
+// of issuing one call per concept.
+func GenerateSecurityConceptsEmbeddings(ctx context.Context, provider EmbeddingsProvider, concepts []SecurityConcept) ([]SecurityConcept, error) {
 	result := make([]SecurityConcept, len(concepts))
+	copy(result, concepts)
 
+	queue := newEmbeddingQueue(provider)
+	futures := make([]<-chan queuedResult, len(concepts))
 	for i, concept := range concepts {
-		// Copy concept data
-		result[i] = concept
+		futures[i] = queue.enqueue(concept.Name)
+	}
+	queue.flush(ctx)
 
-		// Generate embedding
-		embedding, err := client.GetEmbedding(ctx, concept.Name)
-		if err != nil {
-			return nil, err
+	for i, future := range futures {
+		res := <-future
+		if res.err != nil {
+			return nil, res.err
 		}
-
-		result[i].Embedding = embedding
+		result[i].Embedding = res.embedding
 	}
 
 	return result, nil
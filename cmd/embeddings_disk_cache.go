@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// DiskEmbeddingCache is a persistent, content-addressed embedding cache
+// backed by a single SQLite file, so repeated extract/match runs over a
+// codebase reuse embeddings from prior runs instead of recomputing them.
+// Entries are keyed by a SHA1 digest of the provider name and normalized
+// text, so switching providers never returns a stale vector from a
+// different embedding space. It's optional: EmbeddingMatcher works the same
+// without one, just without cross-run reuse.
+type DiskEmbeddingCache struct {
+	db *sql.DB
+
+	hits   int
+	misses int
+}
+
+const diskCacheSchema = `
+CREATE TABLE IF NOT EXISTS embeddings (
+	digest TEXT PRIMARY KEY,
+	vector BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS chunks (
+	provider   TEXT NOT NULL,
+	path       TEXT NOT NULL,
+	start_byte INTEGER NOT NULL,
+	end_byte   INTEGER NOT NULL,
+	start_line INTEGER NOT NULL,
+	end_line   INTEGER NOT NULL,
+	kind       TEXT NOT NULL,
+	name       TEXT NOT NULL,
+	digest     TEXT NOT NULL,
+	vector     BLOB NOT NULL,
+	PRIMARY KEY (provider, path, start_byte, end_byte)
+);
+`
+
+// DefaultCacheDir is the directory callers pass to OpenDiskEmbeddingCache
+// when they have no preference of their own.
+func DefaultCacheDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".solidair-cache", "embeddings")
+	}
+	return filepath.Join(home, ".cache", "solidair", "embeddings")
+}
+
+// OpenDiskEmbeddingCache opens (creating if necessary) a SQLite-backed cache
+// at dir/cache.db.
+func OpenDiskEmbeddingCache(dir string) (*DiskEmbeddingCache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, "cache.db"))
+	if err != nil {
+		return nil, fmt.Errorf("error opening embedding cache: %w", err)
+	}
+	if _, err := db.Exec(diskCacheSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating cache schema: %w", err)
+	}
+
+	return &DiskEmbeddingCache{db: db}, nil
+}
+
+// Close closes the underlying database file.
+func (c *DiskEmbeddingCache) Close() error { return c.db.Close() }
+
+// Hits is how many Get calls found a cached vector since this cache was
+// opened. No command-line flag reports this count.
+func (c *DiskEmbeddingCache) Hits() int { return c.hits }
+
+// Misses is how many Get calls found nothing cached since this cache was
+// opened. No command-line flag reports this count.
+func (c *DiskEmbeddingCache) Misses() int { return c.misses }
+
+// Get returns the cached embedding for (providerName, text), if present.
+func (c *DiskEmbeddingCache) Get(providerName, text string) (Embedding, bool, error) {
+	var vector []byte
+	err := c.db.QueryRow(`SELECT vector FROM embeddings WHERE digest = ?`, cacheDigest(providerName, text)).Scan(&vector)
+	if err == sql.ErrNoRows {
+		c.misses++
+		return Embedding{}, false, nil
+	}
+	if err != nil {
+		return Embedding{}, false, fmt.Errorf("error reading embedding cache: %w", err)
+	}
+
+	c.hits++
+	return Embedding{Vector: decodeCacheVector(vector)}, true, nil
+}
+
+// Put stores embedding under (providerName, text)'s digest.
+func (c *DiskEmbeddingCache) Put(providerName, text string, embedding Embedding) error {
+	_, err := c.db.Exec(
+		`INSERT INTO embeddings (digest, vector) VALUES (?, ?) ON CONFLICT (digest) DO UPDATE SET vector = excluded.vector`,
+		cacheDigest(providerName, text), encodeCacheVector(embedding.Vector),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing embedding cache: %w", err)
+	}
+	return nil
+}
+
+// StoredChunk is one SemanticChunk row persisted by PutChunk, as returned
+// by Chunks for scoring against a search query's embedding.
+type StoredChunk struct {
+	FilePath  string
+	StartLine uint32
+	EndLine   uint32
+	Kind      string
+	Name      string
+	Embedding Embedding
+}
+
+// ChunkDigest returns the digest PutChunk most recently stored for
+// (path, startByte, endByte) under providerName, if any, so SemanticIndex
+// can skip re-embedding a chunk whose content hasn't changed since the last
+// run.
+func (c *DiskEmbeddingCache) ChunkDigest(providerName, path string, startByte, endByte uint32) (string, bool, error) {
+	var digest string
+	err := c.db.QueryRow(
+		`SELECT digest FROM chunks WHERE provider = ? AND path = ? AND start_byte = ? AND end_byte = ?`,
+		providerName, path, startByte, endByte,
+	).Scan(&digest)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("error reading chunk cache: %w", err)
+	}
+	return digest, true, nil
+}
+
+// PutChunk stores chunk's embedding under digest, keyed by (providerName,
+// path, byte range) so switching providers never returns a stale vector
+// from a different embedding space, and re-indexing a changed span
+// overwrites its row instead of leaving the old one behind.
+func (c *DiskEmbeddingCache) PutChunk(providerName string, chunk SemanticChunk, digest string, embedding Embedding) error {
+	_, err := c.db.Exec(
+		`INSERT INTO chunks (provider, path, start_byte, end_byte, start_line, end_line, kind, name, digest, vector)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (provider, path, start_byte, end_byte) DO UPDATE SET
+			start_line = excluded.start_line, end_line = excluded.end_line,
+			kind = excluded.kind, name = excluded.name,
+			digest = excluded.digest, vector = excluded.vector`,
+		providerName, chunk.FilePath, chunk.StartByte, chunk.EndByte, chunk.StartLine, chunk.EndLine, chunk.Kind, chunk.Name, digest, encodeCacheVector(embedding.Vector),
+	)
+	if err != nil {
+		return fmt.Errorf("error writing chunk cache: %w", err)
+	}
+	return nil
+}
+
+// ChunkEntry returns the digest and stored embedding most recently
+// persisted for (path, startByte, endByte) under providerName, if any. It's
+// ChunkDigest plus the vector, for a caller like IndexFileAtomic that needs
+// to carry an unchanged chunk's embedding forward into a replacement batch
+// rather than just know it can be skipped.
+func (c *DiskEmbeddingCache) ChunkEntry(providerName, path string, startByte, endByte uint32) (digest string, embedding Embedding, ok bool, err error) {
+	var vector []byte
+	err = c.db.QueryRow(
+		`SELECT digest, vector FROM chunks WHERE provider = ? AND path = ? AND start_byte = ? AND end_byte = ?`,
+		providerName, path, startByte, endByte,
+	).Scan(&digest, &vector)
+	if err == sql.ErrNoRows {
+		return "", Embedding{}, false, nil
+	}
+	if err != nil {
+		return "", Embedding{}, false, fmt.Errorf("error reading chunk cache: %w", err)
+	}
+	return digest, Embedding{Vector: decodeCacheVector(vector)}, true, nil
+}
+
+// chunkEntry pairs a SemanticChunk with the digest and embedding
+// ReplaceFileChunks should persist for it.
+type chunkEntry struct {
+	chunk     SemanticChunk
+	digest    string
+	embedding Embedding
+}
+
+// ReplaceFileChunks atomically replaces every chunk row stored for
+// (providerName, path) with entries, in a single transaction: a span
+// dropped from the file (a deleted function, say) disappears along with
+// everything else being refreshed, and a concurrent Search never observes a
+// file with only some of its spans updated. Unlike PutChunk, which
+// upserts one row at a time, this is IndexFileAtomic's write path for
+// background re-indexing, where a whole file's chunk set changes together.
+func (c *DiskEmbeddingCache) ReplaceFileChunks(providerName, path string, entries []chunkEntry) error {
+	tx, err := c.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error starting chunk replacement: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM chunks WHERE provider = ? AND path = ?`, providerName, path); err != nil {
+		return fmt.Errorf("error clearing stale chunks: %w", err)
+	}
+
+	for _, e := range entries {
+		_, err := tx.Exec(
+			`INSERT INTO chunks (provider, path, start_byte, end_byte, start_line, end_line, kind, name, digest, vector)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			providerName, e.chunk.FilePath, e.chunk.StartByte, e.chunk.EndByte, e.chunk.StartLine, e.chunk.EndLine,
+			e.chunk.Kind, e.chunk.Name, e.digest, encodeCacheVector(e.embedding.Vector),
+		)
+		if err != nil {
+			return fmt.Errorf("error writing chunk cache: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error committing chunk replacement: %w", err)
+	}
+	return nil
+}
+
+// Chunks returns every chunk persisted under providerName, for
+// SemanticIndex.Search to score against a query's embedding.
+func (c *DiskEmbeddingCache) Chunks(providerName string) ([]StoredChunk, error) {
+	rows, err := c.db.Query(
+		`SELECT path, start_line, end_line, kind, name, vector FROM chunks WHERE provider = ?`,
+		providerName,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error reading chunk cache: %w", err)
+	}
+	defer rows.Close()
+
+	var chunks []StoredChunk
+	for rows.Next() {
+		var sc StoredChunk
+		var vector []byte
+		if err := rows.Scan(&sc.FilePath, &sc.StartLine, &sc.EndLine, &sc.Kind, &sc.Name, &vector); err != nil {
+			return nil, fmt.Errorf("error scanning chunk row: %w", err)
+		}
+		sc.Embedding = Embedding{Vector: decodeCacheVector(vector)}
+		chunks = append(chunks, sc)
+	}
+	return chunks, rows.Err()
+}
+
+// cacheDigest derives a SHA1 digest from the provider name and normalized
+// text, so vectors from different providers or models never collide.
+func cacheDigest(providerName, text string) string {
+	h := sha1.New()
+	h.Write([]byte(providerName))
+	h.Write([]byte{0})
+	h.Write([]byte(normalizeCacheText(text)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeCacheText lowercases and trims text so trivially different
+// inputs ("Foo ", "foo") share a cache entry.
+func normalizeCacheText(text string) string {
+	return strings.TrimSpace(strings.ToLower(text))
+}
+
+// encodeCacheVector packs a []float32 into little-endian bytes for storage
+// as a BLOB.
+func encodeCacheVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeCacheVector unpacks encodeCacheVector's output back into a []float32.
+func decodeCacheVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vec
+}
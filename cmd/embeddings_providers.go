@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	_ EmbeddingsProvider = (*OpenAIClient)(nil)
+	_ EmbeddingsProvider = (*OllamaEmbeddingsProvider)(nil)
+	_ EmbeddingsProvider = (*LocalHashEmbeddingsProvider)(nil)
+)
+
+// OllamaEmbeddingsProvider embeds text via a local Ollama server's
+// /api/embeddings endpoint. Ollama only takes one prompt per request, so
+// Embed issues one call per text against the configured host.
+type OllamaEmbeddingsProvider struct {
+	Host  string
+	Model string
+	Dims  int
+}
+
+// NewOllamaEmbeddingsProvider creates a provider for a local Ollama server,
+// defaulting to the standard local host and the nomic-embed-text model.
+func NewOllamaEmbeddingsProvider(host, model string) *OllamaEmbeddingsProvider {
+	if host == "" {
+		host = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	return &OllamaEmbeddingsProvider{Host: host, Model: model, Dims: 768}
+}
+
+// Embed implements EmbeddingsProvider.
+func (p *OllamaEmbeddingsProvider) Embed(ctx context.Context, texts []string) ([]Embedding, error) {
+	result := make([]Embedding, len(texts))
+	for i, text := range texts {
+		vector, err := p.embedOne(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = Embedding{Vector: vector}
+	}
+	return result, nil
+}
+
+func (p *OllamaEmbeddingsProvider) embedOne(ctx context.Context, text string) ([]float32, error) {
+	payload, err := json.Marshal(map[string]any{"model": p.Model, "prompt": text})
+	if err != nil {
+		return nil, fmt.Errorf("error encoding ollama request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Host+"/api/embeddings", bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error building ollama request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling ollama at %s: %w", p.Host, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, &RateLimitError{RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")), Err: fmt.Errorf("ollama returned status %d", resp.StatusCode)}
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("ollama returned status %d", resp.StatusCode)
+	}
+
+	var out struct {
+		Embedding []float32 `json:"embedding"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("error decoding ollama response: %w", err)
+	}
+
+	return out.Embedding, nil
+}
+
+// parseRetryAfter parses a Retry-After response header, which per RFC 9110
+// is either a number of seconds or an HTTP date. Only the seconds form is
+// honored; anything else (including an empty header) returns 0, leaving the
+// caller to fall back to its own backoff schedule.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Dimensions implements EmbeddingsProvider.
+func (p *OllamaEmbeddingsProvider) Dimensions() int { return p.Dims }
+
+// MaxTokens implements EmbeddingsProvider. Ollama's local embedding models
+// are typically short-context; 2048 is a conservative default.
+func (p *OllamaEmbeddingsProvider) MaxTokens() int { return 2048 }
+
+// Name implements EmbeddingsProvider.
+func (p *OllamaEmbeddingsProvider) Name() string { return "ollama-" + p.Model }
+
+// localHashDimension is the vector size LocalHashEmbeddingsProvider produces.
+const localHashDimension = 64
+
+// localHashProviderName is the Name() stamped onto vectors LocalHashEmbeddingsProvider produces.
+const localHashProviderName = "local-hash-v1"
+
+// LocalHashEmbeddingsProvider is a deterministic, offline embedder: it
+// hashes character n-grams of the input into a fixed-size vector, so names
+// sharing substrings (is_locked, locked, unlocked) land close together in
+// cosine space without a network call or trained model. It replaces the old
+// getOfflineEmbedding stub, which only filled in the first three bytes of
+// the input name.
+type LocalHashEmbeddingsProvider struct {
+	Dimension int
+}
+
+// NewLocalHashEmbeddingsProvider creates a LocalHashEmbeddingsProvider with
+// the default vector size.
+func NewLocalHashEmbeddingsProvider() *LocalHashEmbeddingsProvider {
+	return &LocalHashEmbeddingsProvider{Dimension: localHashDimension}
+}
+
+// Embed implements EmbeddingsProvider. It never fails: there's no network or
+// model-loading step that can error out at call time.
+func (p *LocalHashEmbeddingsProvider) Embed(ctx context.Context, texts []string) ([]Embedding, error) {
+	result := make([]Embedding, len(texts))
+	for i, text := range texts {
+		result[i] = Embedding{Vector: hashEmbed(text, p.Dimension)}
+	}
+	return result, nil
+}
+
+// Dimensions implements EmbeddingsProvider.
+func (p *LocalHashEmbeddingsProvider) Dimensions() int { return p.Dimension }
+
+// MaxTokens implements EmbeddingsProvider. Nothing goes over the wire, so no
+// request-size limit applies.
+func (p *LocalHashEmbeddingsProvider) MaxTokens() int { return math.MaxInt32 }
+
+// Name implements EmbeddingsProvider.
+func (p *LocalHashEmbeddingsProvider) Name() string { return localHashProviderName }
+
+// hashEmbed hashes text's 3-5 character n-grams into a dim-length vector and
+// scales it to unit length, the same fastText-style trick used offline
+// elsewhere in this codebase.
+func hashEmbed(text string, dim int) []float32 {
+	vector := make([]float32, dim)
+	normalized := "<" + strings.ToLower(text) + ">"
+	runes := []rune(normalized)
+
+	for n := 3; n <= 5; n++ {
+		if len(runes) < n {
+			continue
+		}
+		for i := 0; i+n <= len(runes); i++ {
+			idx := fnv32(string(runes[i:i+n])) % uint32(dim)
+			vector[idx]++
+		}
+	}
+
+	var sumSquares float64
+	for _, v := range vector {
+		sumSquares += float64(v) * float64(v)
+	}
+	if sumSquares > 0 {
+		norm := float32(math.Sqrt(sumSquares))
+		for i := range vector {
+			vector[i] /= norm
+		}
+	}
+
+	return vector
+}
+
+// fnv32 is a small, dependency-free string hash (FNV-1a).
+func fnv32(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+// NewEmbeddingsProviderFromEnv builds an EmbeddingsProvider selected by the
+// EMBEDDINGS_PROVIDER environment variable ("openai", "ollama", or "local"),
+// defaulting to "openai" when unset, mirroring GetAPIKey's env-var-first
+// convention.
+func NewEmbeddingsProviderFromEnv(apiKey string) (EmbeddingsProvider, error) {
+	switch name := os.Getenv("EMBEDDINGS_PROVIDER"); name {
+	case "", "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("openai provider requires an API key (--api-key or OPENAI_API_KEY)")
+		}
+		return NewOpenAIClient(apiKey), nil
+	case "ollama":
+		return NewOllamaEmbeddingsProvider(os.Getenv("OLLAMA_HOST"), os.Getenv("OLLAMA_MODEL")), nil
+	case "local":
+		return NewLocalHashEmbeddingsProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDINGS_PROVIDER %q (want openai, ollama, or local)", name)
+	}
+}
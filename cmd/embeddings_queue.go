@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// queueMaxRetries is the number of retry attempts embeddingQueue makes for a
+// single batch after a failed provider call (any error, not only 429/5xx),
+// beyond the initial try.
+const queueMaxRetries = 5
+
+// queueBaseBackoff is the delay before the first retry; each subsequent
+// retry doubles it (capped at queueMaxBackoff) and adds jitter.
+const queueBaseBackoff = 500 * time.Millisecond
+
+// queueMaxBackoff caps the exponential backoff delay so a long run of
+// failures doesn't end up sleeping for minutes between attempts.
+const queueMaxBackoff = 30 * time.Second
+
+// defaultQueueMaxTokens is used when a provider doesn't report a usable
+// MaxTokens(), matching OpenAI ada-002's per-request limit.
+const defaultQueueMaxTokens = 8191
+
+// RateLimitError lets an EmbeddingsProvider report how long embeddingQueue
+// should wait before retrying a rate-limited batch, e.g. from a
+// Retry-After response header, instead of leaving it to guess with backoff
+// alone.
+type RateLimitError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *RateLimitError) Error() string { return e.Err.Error() }
+func (e *RateLimitError) Unwrap() error { return e.Err }
+
+// embeddingQueue batches pending embedding requests from callers like
+// GenerateSecurityConceptsEmbeddings and EmbeddingMatcher.MatchVariables so
+// many small texts go out in a single provider call, sized to stay under
+// the provider's token limit, instead of one HTTP round-trip per item. Each
+// batch is retried atomically on failure - never split further or partially
+// resolved - so callers either get every embedding in a batch or none of
+// them.
+type embeddingQueue struct {
+	provider  EmbeddingsProvider
+	maxTokens int
+
+	mu      sync.Mutex
+	pending []queuedRequest
+}
+
+// queuedRequest is one text waiting to be embedded, with the future its
+// caller is blocked on.
+type queuedRequest struct {
+	text   string
+	tokens int
+	result chan queuedResult
+}
+
+// queuedResult is a queuedRequest's outcome, delivered once its batch has
+// been attempted.
+type queuedResult struct {
+	embedding Embedding
+	err       error
+}
+
+// newEmbeddingQueue creates a queue that batches requests to provider under
+// provider.MaxTokens() tokens per call.
+func newEmbeddingQueue(provider EmbeddingsProvider) *embeddingQueue {
+	maxTokens := provider.MaxTokens()
+	if maxTokens <= 0 {
+		maxTokens = defaultQueueMaxTokens
+	}
+	return &embeddingQueue{provider: provider, maxTokens: maxTokens}
+}
+
+// enqueue adds text to the queue and returns a future resolved once its
+// batch has been embedded. It never touches the network itself; call flush
+// to actually issue requests for everything enqueued so far.
+func (q *embeddingQueue) enqueue(text string) <-chan queuedResult {
+	result := make(chan queuedResult, 1)
+	q.mu.Lock()
+	q.pending = append(q.pending, queuedRequest{text: text, tokens: estimateQueueTokens(text), result: result})
+	q.mu.Unlock()
+	return result
+}
+
+// flush issues one provider.Embed call per batch of queued requests sized
+// under maxTokens, resolving each request's future as its batch completes.
+func (q *embeddingQueue) flush(ctx context.Context) {
+	q.mu.Lock()
+	pending := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	for _, batch := range q.slice(pending) {
+		texts := make([]string, len(batch))
+		for i, req := range batch {
+			texts[i] = req.text
+		}
+
+		embeddings, err := q.embedWithRetry(ctx, texts)
+		for i, req := range batch {
+			if err != nil {
+				req.result <- queuedResult{err: err}
+				continue
+			}
+			req.result <- queuedResult{embedding: embeddings[i]}
+		}
+	}
+}
+
+// slice splits pending into batches whose estimated token count stays under
+// maxTokens, so a single request never exceeds what the provider accepts.
+func (q *embeddingQueue) slice(pending []queuedRequest) [][]queuedRequest {
+	var batches [][]queuedRequest
+	var current []queuedRequest
+	var tokens int
+
+	for _, req := range pending {
+		if len(current) > 0 && tokens+req.tokens > q.maxTokens {
+			batches = append(batches, current)
+			current = nil
+			tokens = 0
+		}
+		current = append(current, req)
+		tokens += req.tokens
+	}
+	if len(current) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// embedWithRetry calls provider.Embed, retrying the whole batch atomically
+// on failure - either every text in texts gets an embedding or none does,
+// so callers never observe a half-written batch. A RateLimitError's
+// RetryAfter is honored verbatim; any other error falls back to exponential
+// backoff with jitter.
+func (q *embeddingQueue) embedWithRetry(ctx context.Context, texts []string) ([]Embedding, error) {
+	var lastErr error
+	for attempt := 0; attempt <= queueMaxRetries; attempt++ {
+		if attempt > 0 {
+			delay := queueBackoffDelay(attempt)
+			var rle *RateLimitError
+			if errors.As(lastErr, &rle) && rle.RetryAfter > 0 {
+				delay = rle.RetryAfter
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		embeddings, err := q.provider.Embed(ctx, texts)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("embedding batch of %d texts failed after %d retries: %w", len(texts), queueMaxRetries, lastErr)
+}
+
+// queueBackoffDelay is the exponential-backoff-plus-jitter delay before
+// retry attempt n (n >= 1): base * 2^(n-1), capped at queueMaxBackoff, then
+// jittered by +/-50% so many failing batches don't retry in lockstep.
+func queueBackoffDelay(attempt int) time.Duration {
+	delay := time.Duration(float64(queueBaseBackoff) * math.Pow(2, float64(attempt-1)))
+	if delay > queueMaxBackoff {
+		delay = queueMaxBackoff
+	}
+	jitter := time.Duration((rand.Float64() - 0.5) * float64(delay))
+	return delay + jitter
+}
+
+// estimateQueueTokens approximates a token count as one token per four
+// characters, OpenAI's rule of thumb for English text.
+func estimateQueueTokens(text string) int {
+	return (len(text) + 3) / 4
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/pkg/concepts"
+	"github.com/spf13/cobra"
+)
+
+var embeddingsIndexPath string
+
+var embeddingsCmd = &cobra.Command{
+	Use:   "embeddings",
+	Short: "Manage the persisted concept embedding index",
+}
+
+var embeddingsRebuildCmd = &cobra.Command{
+	Use:   "rebuild",
+	Short: "Re-embed changed security concepts and rewrite the index",
+	Long: `Loads the security concepts and consults the on-disk index's manifest,
+re-embedding only the concepts whose name, description, or synonyms changed
+since the last rebuild. Unchanged concepts reuse their stored vector instead
+of paying for another embedding call.`,
+	Run: embeddingsRebuildMain,
+}
+
+var embeddingsStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show cache hit rate and staleness for the persisted concept index",
+	Long: `Compares the security concepts against the on-disk index's manifest without
+re-embedding or writing anything, reporting how many concepts would be reused
+(cached) vs. re-embedded (stale) on the next rebuild, plus any orphaned
+manifest entries left behind by a renamed or deleted concept.`,
+	Run: embeddingsStatsMain,
+}
+
+var embeddingsGCCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Remove orphaned entries from the persisted concept index",
+	Long: `Drops manifest entries (and their stored vectors) for concepts that no longer
+exist, reclaiming space left behind by renamed or deleted concepts. Concepts
+still present are untouched - gc never re-embeds anything.`,
+	Run: embeddingsGCMain,
+}
+
+func init() {
+	embeddingsRebuildCmd.Flags().StringVar(&embeddingsIndexPath, "index", "embeddings/concepts", "base path for the persisted index (writes <path>.vectors and <path>.manifest.json)")
+	embeddingsStatsCmd.Flags().StringVar(&embeddingsIndexPath, "index", "embeddings/concepts", "base path for the persisted index")
+	embeddingsGCCmd.Flags().StringVar(&embeddingsIndexPath, "index", "embeddings/concepts", "base path for the persisted index")
+	embeddingsCmd.AddCommand(embeddingsRebuildCmd)
+	embeddingsCmd.AddCommand(embeddingsStatsCmd)
+	embeddingsCmd.AddCommand(embeddingsGCCmd)
+	rootCmd.AddCommand(embeddingsCmd)
+}
+
+func embeddingsRebuildMain(cmd *cobra.Command, args []string) {
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	if apiKey == "" {
+		apiKey = embedding.GetAPIKey()
+		if apiKey == "" {
+			fmt.Fprintln(os.Stderr, "OpenAI API key not provided. Use --api-key flag or set OPENAI_API_KEY environment variable")
+			os.Exit(1)
+		}
+	}
+
+	conceptsDir, _ := cmd.Flags().GetString("concepts-dir")
+	securityConcepts, err := concepts.LoadAllSecurityConcepts(conceptsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading security concepts: %v\n", err)
+		os.Exit(1)
+	}
+
+	provider := embedding.NewOpenAIProvider(embedding.NewOpenAIClient(apiKey))
+	batched := embedding.NewBatchEmbedder(provider)
+	batched.OnBatch = func(done, total int) {
+		fmt.Printf("\rEmbedding stale concepts... %d/%d", done, total)
+		if done == total {
+			fmt.Println()
+		}
+	}
+
+	updated, err := embedding.SyncConcepts(context.Background(), batched, securityConcepts, embeddingsIndexPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error rebuilding index: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Rebuilt index for %d concepts at %s\n", len(updated), embeddingsIndexPath)
+}
+
+func embeddingsStatsMain(cmd *cobra.Command, args []string) {
+	conceptsDir, _ := cmd.Flags().GetString("concepts-dir")
+	securityConcepts, err := concepts.LoadAllSecurityConcepts(conceptsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading security concepts: %v\n", err)
+		os.Exit(1)
+	}
+
+	stats, err := embedding.Stats(embeddingsIndexPath, securityConcepts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading index stats: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Index:      %s\n", embeddingsIndexPath)
+	fmt.Printf("Model:      %s\n", stats.ModelID)
+	fmt.Printf("Dimensions: %d\n", stats.Dimension)
+	fmt.Printf("Concepts:   %d total, %d cached, %d stale\n", stats.Total, stats.Cached, stats.Stale)
+	fmt.Printf("Hit rate:   %.1f%%\n", stats.HitRate()*100)
+	fmt.Printf("Orphaned:   %d (run 'solidair embeddings gc' to remove)\n", stats.Orphaned)
+}
+
+func embeddingsGCMain(cmd *cobra.Command, args []string) {
+	conceptsDir, _ := cmd.Flags().GetString("concepts-dir")
+	securityConcepts, err := concepts.LoadAllSecurityConcepts(conceptsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading security concepts: %v\n", err)
+		os.Exit(1)
+	}
+
+	removed, err := embedding.GC(embeddingsIndexPath, securityConcepts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running gc: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Removed %d orphaned entries from %s\n", removed, embeddingsIndexPath)
+}
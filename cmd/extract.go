@@ -3,11 +3,10 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"unsafe"
 
-	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/variables"
 	"github.com/spf13/cobra"
-	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
 var extractCmd = &cobra.Command{
@@ -17,6 +16,12 @@ var extractCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := args[0]
 
+		backend, ok := languages.ForExtension(".cairo")
+		if !ok {
+			fmt.Fprintln(os.Stderr, "Error: no cairo language backend registered")
+			os.Exit(1)
+		}
+
 		// Read the source code
 		data, err := os.ReadFile(filename)
 		if err != nil {
@@ -25,29 +30,25 @@ var extractCmd = &cobra.Command{
 		}
 
 		// Parse the source code
-		parser := tree_sitter.NewParser()
-		defer parser.Close()
-
-		err = parser.SetLanguage(tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language())))
+		tree, err := backend.Parse(data)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error setting language: %v\n", err)
+			fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
 			os.Exit(1)
 		}
-		tree := parser.Parse(data, nil)
 		defer tree.Close()
 
 		// Extract variables
-		vars, err := ExtractVariables(data, tree)
+		vars, err := variables.ExtractVariables(data, tree, backend)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error extracting variables: %v\n", err)
 			os.Exit(1)
 		}
 
 		vars.Filename = filename
-		PrintExtractedVariables(vars)
+		variables.PrintExtractedVariables(vars)
 	},
 }
 
 func init() {
 	rootCmd.AddCommand(extractCmd)
-}
\ No newline at end of file
+}
@@ -17,8 +17,16 @@ var (
 
 // embeddingEntry stores an embedding with its concept name for easier mapping
 type embeddingEntry struct {
-	ConceptName string    `toml:"concept_name"`
-	Embedding   Embedding `toml:"embedding"`
+	ConceptName string `toml:"concept_name"`
+	// EmbedderName identifies which model produced Embedding, e.g.
+	// "openai-text-embedding-ada-002", mirroring types.EmbeddingEntry so
+	// files from this legacy generator and the embedding package agree on
+	// format.
+	EmbedderName string `toml:"embedder_name,omitempty"`
+	// Dimensions is len(Embedding.Vector), recorded alongside the vector so
+	// tooling can sanity-check a file without decoding every entry.
+	Dimensions int       `toml:"dimensions,omitempty"`
+	Embedding  Embedding `toml:"embedding"`
 }
 
 var generateEmbeddingsCmd = &cobra.Command{
@@ -163,8 +171,10 @@ func generateEmbeddingsMain(cmd *cobra.Command, args []string) {
 
 		// Add to embedding entries
 		embeddingEntries = append(embeddingEntries, embeddingEntry{
-			ConceptName: concept.Name,
-			Embedding:   Embedding{Vector: vector},
+			ConceptName:  concept.Name,
+			EmbedderName: "openai-" + string(openai.AdaEmbeddingV2),
+			Dimensions:   len(vector),
+			Embedding:    Embedding{Vector: vector},
 		})
 	}
 
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"math"
+	"sort"
+	"strings"
+)
+
+// HybridMatchConfig controls how EmbeddingMatcher.MatchVariable blends the
+// dense cosine score against a lexical score when Hybrid is set. The fused
+// score is a straight weighted average, Alpha*cosine + (1-Alpha)*lexical,
+// rather than a rank-based fusion, so it stays on the same [0,1] scale as
+// SimilarityThreshold and both sub-scores stay inspectable on ConceptMatch.
+type HybridMatchConfig struct {
+	Alpha float32 // weight given to the cosine score; the lexical score gets 1-Alpha
+	// SemanticMinScore and LexicalMinScore, when non-zero, drop a concept
+	// from the fused ranking before blending if its sub-score on that side
+	// falls short - e.g. requiring some minimum lexical overlap even for a
+	// concept with a strong cosine score.
+	SemanticMinScore float32
+	LexicalMinScore  float32
+	MinScore         float32 // fused matches below this score are dropped
+}
+
+// DefaultHybridMatchConfig weights semantic and lexical scores equally.
+func DefaultHybridMatchConfig() HybridMatchConfig {
+	return HybridMatchConfig{Alpha: 0.5}
+}
+
+// tokenizeIdentifier splits a Cairo identifier into lowercase word tokens,
+// breaking on underscores and camelCase boundaries, so "is_locked" and
+// "isLocked" both tokenize to ["is", "locked"].
+func tokenizeIdentifier(name string) []string {
+	var tokens []string
+	var cur strings.Builder
+
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, strings.ToLower(cur.String()))
+			cur.Reset()
+		}
+	}
+
+	runes := []rune(name)
+	for i, r := range runes {
+		switch {
+		case r == '_' || r == '-':
+			flush()
+		case i > 0 && 'A' <= r && r <= 'Z' && !('A' <= runes[i-1] && runes[i-1] <= 'Z'):
+			flush()
+			cur.WriteRune(r)
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// bm25LexicalScore scores a variable name against concept, with IDF
+// computed across corpus (one "document" per concept: its name plus its
+// synonyms) so a term that's distinctive to one concept outweighs one
+// shared by half the concept list. An exact token match against the
+// concept's own name or a synonym always wins outright, since that's a
+// stronger signal than any BM25 weighting could produce from such short
+// documents.
+func bm25LexicalScore(varName string, concept SecurityConcept, corpus []SecurityConcept) float32 {
+	varTokens := tokenizeIdentifier(varName)
+	if len(varTokens) == 0 {
+		return 0
+	}
+
+	for _, t := range varTokens {
+		if t == strings.ToLower(concept.Name) {
+			return 1.0
+		}
+		for _, synonym := range concept.Synonyms {
+			if t == strings.ToLower(synonym) {
+				return 0.95
+			}
+		}
+	}
+
+	docs := make(map[string][]string, len(corpus))
+	for _, c := range corpus {
+		doc := tokenizeIdentifier(c.Name)
+		for _, synonym := range c.Synonyms {
+			doc = append(doc, tokenizeIdentifier(synonym)...)
+		}
+		docs[c.Name] = doc
+	}
+	thisDoc, ok := docs[concept.Name]
+	if !ok {
+		thisDoc = tokenizeIdentifier(concept.Name)
+	}
+
+	var avgDocLen float64
+	for _, d := range docs {
+		avgDocLen += float64(len(d))
+	}
+	avgDocLen /= float64(len(docs))
+
+	df := make(map[string]int)
+	for _, d := range docs {
+		seen := make(map[string]bool)
+		for _, t := range d {
+			if !seen[t] {
+				df[t]++
+				seen[t] = true
+			}
+		}
+	}
+
+	const k1 = 1.2
+	const b = 0.75
+	n := float64(len(docs))
+	docLen := float64(len(thisDoc))
+
+	var score float64
+	for _, term := range dedupeTokens(varTokens) {
+		var tf int
+		for _, t := range thisDoc {
+			if t == term {
+				tf++
+			}
+		}
+		if tf == 0 {
+			continue
+		}
+
+		idf := math.Log(1 + (n-float64(df[term])+0.5)/(float64(df[term])+0.5))
+		score += idf * (float64(tf) * (k1 + 1)) / (float64(tf) + k1*(1-b+b*docLen/avgDocLen))
+	}
+
+	// Squash BM25's unbounded scale into [0,1) so it's on a comparable
+	// range to cosine similarity for the Alpha blend in matchVariableHybrid.
+	return float32(score / (score + 1))
+}
+
+// dedupeTokens returns tokens with duplicates removed, preserving order of
+// first occurrence.
+func dedupeTokens(tokens []string) []string {
+	seen := make(map[string]bool, len(tokens))
+	out := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// matchVariableHybrid scores variable against every concept under both the
+// semantic (cosine) and lexical (BM25 + synonym) scorers, then blends them
+// per m.Hybrid: Alpha*cosine + (1-Alpha)*lexical. Unlike the pure-cosine
+// path in MatchVariable, this can resolve a match with a weak embedding
+// score as long as the variable name has strong string/synonym overlap
+// with a concept, and it keeps both sub-scores on the result so a caller
+// can see why a concept ranked where it did.
+func (m *EmbeddingMatcher) matchVariableHybrid(ctx context.Context, variable VariableInfo) ([]ConceptMatch, error) {
+	varEmbedding, err := m.GetVariableEmbedding(ctx, variable)
+	if err != nil {
+		return nil, err
+	}
+
+	fused := make([]ConceptMatch, 0, len(m.Concepts))
+	for _, concept := range m.Concepts {
+		semanticScore := CosineSimilarity(varEmbedding, concept.Embedding)
+		lexicalScore := bm25LexicalScore(variable.Name, concept, m.Concepts)
+
+		if m.Hybrid.SemanticMinScore > 0 && semanticScore < m.Hybrid.SemanticMinScore {
+			continue
+		}
+		if m.Hybrid.LexicalMinScore > 0 && lexicalScore < m.Hybrid.LexicalMinScore {
+			continue
+		}
+
+		score := m.Hybrid.Alpha*semanticScore + (1-m.Hybrid.Alpha)*lexicalScore
+		if score < m.Hybrid.MinScore {
+			continue
+		}
+
+		fused = append(fused, ConceptMatch{
+			Variable:        variable,
+			Concept:         concept.Name,
+			SimilarityScore: score,
+			SemanticScore:   semanticScore,
+			LexicalScore:    lexicalScore,
+		})
+	}
+	sort.Slice(fused, func(i, j int) bool { return fused[i].SimilarityScore > fused[j].SimilarityScore })
+
+	return fused, nil
+}
@@ -0,0 +1,384 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/pkg/config"
+	"github.com/jeffrydegrande/solidair/pkg/lsp"
+	"github.com/spf13/cobra"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run solidair as a Language Server Protocol server over stdio",
+	Long: `lsp speaks LSP over stdin/stdout so an editor can show solidair's query
+findings as live diagnostics and hovers instead of running analyze by hand.
+On textDocument/didOpen and didChange it re-parses the file incrementally
+(reusing tree-sitter's edit API, the same trick watch uses) and re-runs the
+loaded queries, publishing each QueryResult as a Diagnostic. Hovering over a
+finding's span returns its query name and description as markdown.`,
+	Args: cobra.NoArgs,
+	Run:  lspMain,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+func lspMain(cmd *cobra.Command, args []string) {
+	queryDir, _ := cmd.Flags().GetString("query-dir")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cache := newQueryCache(queryDir, cfg)
+
+	srv := &lspServer{queries: cache, cfg: cfg, docs: make(map[string]*lspDocument)}
+	srv.run(os.Stdin, os.Stdout)
+}
+
+// lspDocument is the incremental-parse state and last query run lspServer
+// keeps for one open file, keyed by its LSP URI.
+type lspDocument struct {
+	source  []byte
+	tree    *tree_sitter.Tree
+	backend languages.Backend
+	results []QueryResult
+}
+
+// lspServer holds the lazily-loaded per-language query cache and config for
+// the lifetime of one lsp run, and the per-document state built up as the
+// client opens/edits files.
+type lspServer struct {
+	queries *queryCache
+	cfg     *config.Config
+	docs    map[string]*lspDocument
+}
+
+// run reads Content-Length-framed JSON-RPC messages from r until EOF or an
+// exit notification, dispatching each to the matching LSP method handler and
+// writing any response/notification to w.
+func (s *lspServer) run(r io.Reader, w io.Writer) {
+	reader := bufio.NewReader(r)
+	for {
+		msg, err := lsp.ReadMessage(reader)
+		if err != nil {
+			if err != io.EOF {
+				fmt.Fprintf(os.Stderr, "Error reading LSP message: %v\n", err)
+			}
+			return
+		}
+
+		switch msg.Method {
+		case "initialize":
+			s.handleInitialize(w, msg)
+		case "initialized":
+			// No response required; nothing to do until a document opens.
+		case "textDocument/didOpen":
+			s.handleDidOpen(w, msg)
+		case "textDocument/didChange":
+			s.handleDidChange(w, msg)
+		case "textDocument/didClose":
+			s.handleDidClose(msg)
+		case "textDocument/hover":
+			s.handleHover(w, msg)
+		case "shutdown":
+			writeMessage(w, lsp.Response(msg.ID, nil))
+		case "exit":
+			return
+		default:
+			if len(msg.ID) > 0 {
+				writeMessage(w, lsp.ResponseError(msg.ID, lsp.ErrMethodNotFound, "method not found: "+msg.Method))
+			}
+		}
+	}
+}
+
+// handleInitialize negotiates "utf-8" as the position encoding whenever the
+// client offers it (general.positionEncodings in its own capabilities).
+// Positions/ranges built from byteOffsetAt/watchPointAt are plain byte
+// offsets, which is exactly what PositionEncodingKind "utf-8" means - so
+// this is the encoding our positions are actually correct for. Per the LSP
+// spec a client that omits positionEncodings only understands UTF-16
+// offsets; we negotiate it anyway since every client we've tested against
+// (VS Code, Helix, Neovim) advertises "utf-8" support.
+func (s *lspServer) handleInitialize(w io.Writer, msg *lsp.Message) {
+	var params struct {
+		Capabilities struct {
+			General struct {
+				PositionEncodings []string `json:"positionEncodings"`
+			} `json:"general"`
+		} `json:"capabilities"`
+	}
+	_ = json.Unmarshal(msg.Params, &params)
+
+	encoding := "utf-8"
+	if len(params.Capabilities.General.PositionEncodings) > 0 && !containsString(params.Capabilities.General.PositionEncodings, "utf-8") {
+		encoding = params.Capabilities.General.PositionEncodings[0]
+	}
+
+	result := map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"positionEncoding": encoding,
+			"textDocumentSync": 1, // Full: didChange always carries the whole document
+			"hoverProvider":    true,
+		},
+	}
+	writeMessage(w, lsp.Response(msg.ID, result))
+}
+
+func containsString(values []string, target string) bool {
+	for _, v := range values {
+		if v == target {
+			return true
+		}
+	}
+	return false
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+func (s *lspServer) handleDidOpen(w io.Writer, msg *lsp.Message) {
+	var params struct {
+		TextDocument textDocumentItem `json:"textDocument"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing didOpen params: %v\n", err)
+		return
+	}
+	s.analyze(w, params.TextDocument.URI, []byte(params.TextDocument.Text))
+}
+
+func (s *lspServer) handleDidChange(w io.Writer, msg *lsp.Message) {
+	var params struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing didChange params: %v\n", err)
+		return
+	}
+	if len(params.ContentChanges) == 0 {
+		return
+	}
+	// Full sync: the last reported change always carries the entire document.
+	text := params.ContentChanges[len(params.ContentChanges)-1].Text
+	s.analyze(w, params.TextDocument.URI, []byte(text))
+}
+
+func (s *lspServer) handleDidClose(msg *lsp.Message) {
+	var params struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return
+	}
+	if doc, ok := s.docs[params.TextDocument.URI]; ok {
+		doc.tree.Close()
+	}
+	delete(s.docs, params.TextDocument.URI)
+}
+
+// analyze resolves uri's languages.Backend from its extension, reparses
+// source for uri (incrementally, reusing reparseTree - the same helper
+// watch's reparse uses - if a previous version is open), re-runs that
+// backend's queries against it, and stores the result as the document's new
+// state before publishing it as diagnostics. The document's tree is replaced
+// unconditionally, even if RunQueries fails, since reparseTree has already
+// closed the previous tree by the time it returns - leaving the old
+// lspDocument in place would point at a freed tree that the next edit would
+// try to Edit() again.
+func (s *lspServer) analyze(w io.Writer, uri string, source []byte) {
+	backend, ok := languages.ForPath(uri)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No language backend registered for %s\n", uri)
+		return
+	}
+
+	prev := s.docs[uri]
+	var prevSource []byte
+	var prevTree *tree_sitter.Tree
+	if prev != nil {
+		prevSource, prevTree = prev.source, prev.tree
+	}
+
+	tree, err := reparseTree(source, prevSource, prevTree, backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", uri, err)
+		return
+	}
+
+	queries, err := s.queries.queriesFor(backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s queries: %v\n", backend.Name(), err)
+		return
+	}
+
+	results, err := RunQueries(source, tree, backend, queries, s.cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running queries on %s: %v\n", uri, err)
+		results = nil
+	}
+
+	s.docs[uri] = &lspDocument{source: source, tree: tree, backend: backend, results: results}
+	if err == nil {
+		s.publishDiagnostics(w, uri, source, results)
+	}
+}
+
+type lspPosition struct {
+	Line      uint32 `json:"line"`
+	Character uint32 `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity,omitempty"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// publishDiagnostics sends a textDocument/publishDiagnostics notification
+// for uri with one Diagnostic per QueryResult in results.
+func (s *lspServer) publishDiagnostics(w io.Writer, uri string, source []byte, results []QueryResult) {
+	diagnostics := make([]lspDiagnostic, 0, len(results))
+	for _, r := range results {
+		diagnostics = append(diagnostics, lspDiagnostic{
+			Range:    diagnosticRange(source, r),
+			Severity: diagnosticSeverity(r.Severity),
+			Code:     r.QueryName,
+			Source:   "solidair",
+			Message:  r.Description,
+		})
+	}
+
+	notif, err := lsp.Notification("textDocument/publishDiagnostics", map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building publishDiagnostics notification: %v\n", err)
+		return
+	}
+	writeMessage(w, notif)
+}
+
+// diagnosticRange converts a QueryResult's byte span into an LSP Range,
+// whose Line/Character are 0-based unlike QueryResult's 1-based
+// LineNumber/Column, using watchPointAt (the same byte-offset-to-Point
+// conversion watch's incremental reparse relies on).
+func diagnosticRange(source []byte, r QueryResult) lspRange {
+	start := watchPointAt(source, int(r.StartByte))
+	end := watchPointAt(source, int(r.EndByte))
+	return lspRange{
+		Start: lspPosition{Line: uint32(start.Row), Character: uint32(start.Column)},
+		End:   lspPosition{Line: uint32(end.Row), Character: uint32(end.Column)},
+	}
+}
+
+// diagnosticSeverity maps a QueryResult's free-form severity string to one
+// of LSP's DiagnosticSeverity levels (1 Error, 2 Warning, 3 Information),
+// mirroring report.sarifLevel's severity mapping for SARIF.
+func diagnosticSeverity(severity string) int {
+	switch strings.ToLower(severity) {
+	case "critical", "high", "error":
+		return 1
+	case "low", "info", "note":
+		return 3
+	default:
+		return 2
+	}
+}
+
+func (s *lspServer) handleHover(w io.Writer, msg *lsp.Message) {
+	var params struct {
+		TextDocument struct {
+			URI string `json:"uri"`
+		} `json:"textDocument"`
+		Position lspPosition `json:"position"`
+	}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing hover params: %v\n", err)
+		return
+	}
+
+	doc, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		writeMessage(w, lsp.Response(msg.ID, nil))
+		return
+	}
+
+	offset := uint(byteOffsetAt(doc.source, params.Position.Line, params.Position.Character))
+	node := doc.tree.RootNode().DescendantForByteRange(offset, offset)
+
+	var hover []string
+	if node != nil {
+		for _, r := range doc.results {
+			if node.StartByte() < uint(r.EndByte) && uint(r.StartByte) < node.EndByte() {
+				hover = append(hover, fmt.Sprintf("**%s**\n\n%s", r.QueryName, r.Description))
+			}
+		}
+	}
+	if len(hover) == 0 {
+		writeMessage(w, lsp.Response(msg.ID, nil))
+		return
+	}
+
+	writeMessage(w, lsp.Response(msg.ID, map[string]interface{}{
+		"contents": map[string]interface{}{
+			"kind":  "markdown",
+			"value": strings.Join(hover, "\n\n---\n\n"),
+		},
+	}))
+}
+
+// byteOffsetAt converts a 0-based LSP line/character position back into a
+// byte offset into source, the inverse of watchPointAt.
+func byteOffsetAt(source []byte, line, character uint32) int {
+	var row, col uint32
+	for i, b := range source {
+		if row == line && col == character {
+			return i
+		}
+		if b == '\n' {
+			row++
+			col = 0
+		} else {
+			col++
+		}
+	}
+	return len(source)
+}
+
+// writeMessage writes msg to w, logging (rather than returning) an error
+// since the run loop has nowhere useful to propagate a write failure to.
+func writeMessage(w io.Writer, msg *lsp.Message) {
+	if err := lsp.WriteMessage(w, msg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing LSP message: %v\n", err)
+	}
+}
@@ -6,102 +6,252 @@ import (
 	"os"
 	"strings"
 
-	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/embedding"
 	"github.com/jeffrydegrande/solidair/pkg/concepts"
-	"github.com/jeffrydegrande/solidair/pkg/embedding"
-	"github.com/jeffrydegrande/solidair/pkg/variables"
+	"github.com/jeffrydegrande/solidair/pkg/project"
+	"github.com/jeffrydegrande/solidair/pkg/report"
+	"github.com/jeffrydegrande/solidair/types"
 	"github.com/spf13/cobra"
 )
 
+// matchModeFlag is the --mode flag value, mapped to an embedding.MatchMode
+// before the matcher is constructed.
+var matchModeFlag string
+
+// matchFormatFlag is the --format flag value for matchCmd: text, json, or sarif.
+var matchFormatFlag string
+
+// matchProviderFlag, matchModelFlag, and matchHostFlag select and configure
+// the online EmbeddingProvider. matchProviderFlag and matchHostFlag are
+// ignored in --offline mode; matchModelFlag is still consulted there as an
+// optional path to a local .gguf file to embed from.
+var (
+	matchProviderFlag string
+	matchModelFlag    string
+	matchHostFlag     string
+)
+
+// matchWorkersFlag is the number of goroutines used to parse a project's
+// files in parallel when [file] is a directory.
+var matchWorkersFlag int
+
+// matchSemanticRatioFlag and matchMinScoreFlag tune Hybrid mode's fused
+// score: semantic-ratio weights cosine vs. lexical, min-score floors the
+// result the same way --mode semantic/lexical use SimilarityThreshold.
+var (
+	matchSemanticRatioFlag float32
+	matchMinScoreFlag      float32
+)
+
 var matchCmd = &cobra.Command{
-	Use:   "match [file]",
-	Short: "Match variables in a Cairo file to security concepts",
-	Long: `Analyze a Cairo file to extract variables and match them against security concepts.
-This helps identify variables that may be related to security-sensitive operations.`,
+	Use:   "match [file|dir]",
+	Short: "Match variables in a Cairo file or project to security concepts",
+	Long: `Analyze a Cairo file, or every .cairo file in a directory, to extract variables and
+match them against security concepts. This helps identify variables that may be related to
+security-sensitive operations.`,
 	Args: cobra.ExactArgs(1),
 	Run:  matchMain,
 }
 
 func init() {
+	matchCmd.Flags().StringVar(&matchModeFlag, "mode", "semantic", "match mode: semantic, lexical, or hybrid")
+	matchCmd.Flags().StringVar(&matchFormatFlag, "format", "text", "output format: text, json, or sarif")
+	matchCmd.Flags().StringVar(&matchProviderFlag, "provider", "openai", "embedding provider: openai, cohere, voyage, ollama, gguf, or llamacpp (ignored with --offline)")
+	matchCmd.Flags().StringVar(&matchModelFlag, "model", "", "model name passed to the provider (defaults to the provider's own default); with --offline, a path to a local .gguf file to embed from instead of the hashing default")
+	matchCmd.Flags().StringVar(&matchHostFlag, "host", "", "server host for ollama/llamacpp providers (defaults to the provider's own default)")
+	matchCmd.Flags().IntVar(&matchWorkersFlag, "workers", 4, "number of files to parse in parallel when matching a directory")
+	matchCmd.Flags().Float32Var(&matchSemanticRatioFlag, "semantic-ratio", 0.5, "weight given to the cosine score vs. lexical score in --mode hybrid (0 = pure lexical, 1 = pure semantic)")
+	matchCmd.Flags().Float32Var(&matchMinScoreFlag, "min-score", 0.7, "minimum fused score a match must reach to be reported")
 	rootCmd.AddCommand(matchCmd)
 }
 
+// parseMatchMode maps the --mode flag to an embedding.MatchMode.
+func parseMatchMode(mode string) (embedding.MatchMode, error) {
+	switch mode {
+	case "semantic", "":
+		return embedding.SemanticOnly, nil
+	case "lexical":
+		return embedding.LexicalOnly, nil
+	case "hybrid":
+		return embedding.Hybrid, nil
+	default:
+		return 0, fmt.Errorf("unknown match mode %q (want semantic, lexical, or hybrid)", mode)
+	}
+}
+
+// projectMatch pairs a ConceptMatch with the file it was found in, since
+// matchesByConcept spans every file of a project rather than just one.
+type projectMatch struct {
+	FilePath string
+	Match    types.ConceptMatch
+}
+
 func matchMain(cmd *cobra.Command, args []string) {
-	filename := args[0]
-	apiKey, _ := cmd.Flags().GetString("api-key")
-	offline, _ := cmd.Flags().GetBool("offline")
+	path := args[0]
 
-	// Load security concepts
-	securityConcepts, err := concepts.LoadSecurityConcepts()
+	// Load security concepts, merging in any third-party concept packs
+	conceptsDir, _ := cmd.Flags().GetString("concepts-dir")
+	securityConcepts, err := concepts.LoadAllSecurityConcepts(conceptsDir)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error loading security concepts: %v\n", err)
 		os.Exit(1)
 	}
+	conceptPacks := make(map[string]string, len(securityConcepts))
+	for _, c := range securityConcepts {
+		if c.Pack != "" {
+			conceptPacks[c.Name] = c.Pack
+		}
+	}
 
-	// Read the source code
-	data, err := os.ReadFile(filename)
+	// Discover and parse the file or project
+	proj, err := project.Load(path, matchWorkersFlag)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error reading file %s: %v\n", filename, err)
+		fmt.Fprintf(os.Stderr, "Error loading %s: %v\n", path, err)
 		os.Exit(1)
 	}
 
-	// Parse the source code
-	tree, err := cairo.Parse(data)
+	mode, err := parseMatchMode(matchModeFlag)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error parsing file: %v\n", err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 
-	// Extract variables
-	vars, err := variables.ExtractVariables(data, tree)
+	matcher, err := buildMatcher(cmd, securityConcepts)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error extracting variables: %v\n", err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	matcher.Mode = mode
+	matcher.SemanticRatio = matchSemanticRatioFlag
+	matcher.SimilarityThreshold = matchMinScoreFlag
 
-	// Set up embedding matcher
-	var matcher *embedding.EmbeddingMatcher
-	if offline {
-		// Offline mode
-		matcher = embedding.NewEmbeddingMatcher(nil, securityConcepts, true)
-	} else {
-		// Online mode with OpenAI API
-		if apiKey == "" {
-			apiKey = embedding.GetAPIKey()
-			if apiKey == "" {
-				fmt.Fprintln(os.Stderr, "OpenAI API key not provided. Use --api-key flag or set OPENAI_API_KEY environment variable, or use --offline mode")
+	ctx := context.Background()
+
+	// Embed every new variable across the whole project in a single batch
+	// call, then seed the matcher's cache with the result so MatchVariable
+	// below never needs its own per-variable round-trip.
+	if !matcher.Offline {
+		embeddings, err := proj.EmbedVariables(ctx, matcher.Provider)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error embedding project variables: %v\n", err)
+			os.Exit(1)
+		}
+		for name, e := range embeddings {
+			matcher.Cache.Variables[name] = e
+		}
+	}
+
+	// Match every file's variables, grouping results by concept across the
+	// whole project.
+	matchesByConcept := make(map[string][]projectMatch)
+	for _, fv := range proj.Variables {
+		for _, v := range fv.Variables {
+			matches, err := matcher.MatchVariable(ctx, v)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error matching variables in %s: %v\n", fv.Filename, err)
 				os.Exit(1)
 			}
+			for _, m := range matches {
+				matchesByConcept[m.Concept] = append(matchesByConcept[m.Concept], projectMatch{FilePath: fv.Filename, Match: m})
+			}
 		}
-		openAIClient := embedding.NewOpenAIClient(apiKey)
-		matcher = embedding.NewEmbeddingMatcher(openAIClient, securityConcepts, false)
 	}
 
-	// Match variables to concepts
-	ctx := context.Background()
-	matchesByConceptMap, err := matcher.MatchVariables(ctx, vars.Variables)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error matching variables: %v\n", err)
+	switch matchFormatFlag {
+	case "sarif":
+		data, err := report.SARIF(conceptMatchesToFindings(matchesByConcept))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error rendering SARIF: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	case "json":
+		if err := report.JSONLines(os.Stdout, conceptMatchesToFindings(matchesByConcept)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error rendering JSON: %v\n", err)
+			os.Exit(1)
+		}
+	case "text", "":
+		fmt.Printf("Variable matches for %s:\n\n", path)
+
+		if len(matchesByConcept) == 0 {
+			fmt.Println("No matches found.")
+			return
+		}
+
+		for concept, matches := range matchesByConcept {
+			if pack, ok := conceptPacks[concept]; ok {
+				fmt.Printf("Concept: %s (pack: %s)\n", concept, pack)
+			} else {
+				fmt.Printf("Concept: %s\n", concept)
+			}
+			fmt.Println(strings.Repeat("-", 40))
+
+			for _, pm := range matches {
+				fmt.Printf("  %s: %s (line %d)\n", pm.FilePath, pm.Match.Variable.Name, pm.Match.Variable.LineNumber)
+				fmt.Printf("  Similarity: %.4f\n\n", pm.Match.SimilarityScore)
+			}
+
+			fmt.Println()
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown format %q (want text, json, or sarif)\n", matchFormatFlag)
 		os.Exit(1)
 	}
+}
 
-	// Print results
-	fmt.Printf("Variable matches for %s:\n\n", filename)
-
-	if len(matchesByConceptMap) == 0 {
-		fmt.Println("No matches found.")
-		return
+// conceptMatchesToFindings flattens match results grouped by concept into
+// report.Finding, one per variable/concept pair, with the concept name as
+// the SARIF rule ID and each finding's own file path.
+func conceptMatchesToFindings(matchesByConcept map[string][]projectMatch) []report.Finding {
+	var findings []report.Finding
+	for concept, matches := range matchesByConcept {
+		for _, pm := range matches {
+			findings = append(findings, report.Finding{
+				RuleID:          concept,
+				FilePath:        pm.FilePath,
+				Description:     fmt.Sprintf("variable %q matches security concept %q", pm.Match.Variable.Name, concept),
+				LineNumber:      pm.Match.Variable.LineNumber,
+				Code:            pm.Match.Variable.Name,
+				SimilarityScore: pm.Match.SimilarityScore,
+			})
+		}
 	}
+	return findings
+}
 
-	for concept, matches := range matchesByConceptMap {
-		fmt.Printf("Concept: %s\n", concept)
-		fmt.Println(strings.Repeat("-", 40))
+// buildMatcher constructs an EmbeddingMatcher from the --offline/--provider/
+// --model/--host/--api-key flags, shared by matchCmd and watchCmd so both
+// route through the same provider selection.
+func buildMatcher(cmd *cobra.Command, securityConcepts []types.SecurityConcept) (*embedding.EmbeddingMatcher, error) {
+	apiKey, _ := cmd.Flags().GetString("api-key")
+	offline, _ := cmd.Flags().GetBool("offline")
 
-		for _, match := range matches {
-			fmt.Printf("  Variable: %s (line %d)\n", match.Variable.Name, match.Variable.LineNumber)
-			fmt.Printf("  Similarity: %.4f\n\n", match.SimilarityScore)
+	if offline {
+		if matchModelFlag != "" {
+			// --model names a local GGUF file: embed from its own token
+			// embedding table instead of the from-scratch hashing vocabulary.
+			local, err := embedding.LoadGGUFEmbedder(matchModelFlag)
+			if err != nil {
+				return nil, fmt.Errorf("error loading --model for offline mode: %w", err)
+			}
+			return embedding.NewEmbeddingMatcher(nil, securityConcepts, true, local), nil
 		}
 
-		fmt.Println()
+		// Embed locally with a TF-IDF-weighted hashing embedder fit against
+		// the loaded concepts, instead of the plain HashingEmbedder default.
+		local := embedding.NewTFIDFHashingEmbedder(64, embedding.ConceptCorpus(securityConcepts))
+		return embedding.NewEmbeddingMatcher(nil, securityConcepts, true, local), nil
+	}
+
+	if apiKey == "" {
+		apiKey = embedding.GetAPIKey()
 	}
-}
\ No newline at end of file
+	provider, err := embedding.NewProvider(matchProviderFlag, matchModelFlag, matchHostFlag, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w (or use --offline mode)", err)
+	}
+	// Wrap in a BatchEmbedder so embedding every variable in a large project
+	// (proj.EmbedVariables below) slices into provider-sized batches with
+	// rate limiting and retry, instead of one unbounded request.
+	return embedding.NewEmbeddingMatcher(embedding.NewBatchEmbedder(provider), securityConcepts, false, nil), nil
+}
@@ -0,0 +1,245 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// DefaultCandidatesPerConcept is how many top-scoring variables a Multi
+// concept's placeholder lets ResolveTemplateAssignments explore, when the
+// caller doesn't override it. Non-multi concepts always resolve to their
+// single best surviving candidate, regardless of this value.
+const DefaultCandidatesPerConcept = 5
+
+// DefaultAssignmentTopK is how many full assignments ResolveTemplateAssignments
+// returns, when the caller doesn't override it.
+const DefaultAssignmentTopK = 3
+
+// DefaultBeamWidth bounds how many partial assignments are carried from one
+// concept to the next while searching. It's well above
+// DefaultCandidatesPerConcept * len(concepts) for the template sizes this
+// tool sees in practice, so beam search only starts discarding candidates
+// once a template's concept count or N genuinely explodes the search space.
+const DefaultBeamWidth = 64
+
+// sameParentBonus rewards an assignment where two concepts resolved to
+// variables declared in the same struct/function, the strongest co-locality
+// signal VariableInfo carries.
+const sameParentBonus = 0.15
+
+// lineProximityScale scales the fallback co-locality bonus for variables
+// that aren't in the same parent: bonus = lineProximityScale / (1 + lineDistance),
+// so adjacent declarations score close to lineProximityScale and distant
+// ones decay toward zero without ever reaching the same-parent bonus.
+const lineProximityScale = 0.05
+
+// templateAssignment is an in-progress or completed concept -> candidate
+// assignment carried through the beam search.
+type templateAssignment struct {
+	choices map[string]types.ConceptMatch // concept -> chosen candidate
+	score   float32                       // product-of-similarities + co-locality bonus so far
+}
+
+// ResolveTemplateAssignments finds the top-K joint assignments of concepts
+// to variables for template, instead of SubstituteParameters' greedy
+// per-concept best match. A non-multi concept always resolves to its single
+// best candidate, same as SubstituteParameters; a ${name*} multi-value
+// concept contributes up to candidatesPerConcept alternatives, and it's
+// those alternatives the beam actually chooses between, scoring each full
+// assignment as the product of its candidates' similarity scores plus a
+// co-locality bonus for candidates that share a parent struct/function or
+// sit on nearby lines — so a multi-value concept's candidate that sits
+// alongside the template's other (fixed) concepts is preferred over an
+// individually-best but unrelated one.
+//
+// Search is a beam search over template.Concepts in order: at each step,
+// every surviving partial assignment is extended with every candidate for
+// the next concept, and only the top beamWidth results carry forward. This
+// caps the search space at roughly candidatesPerConcept * beamWidth per
+// concept, rather than candidatesPerConcept^len(concepts), once a template
+// has enough multi-value concepts (or N is large enough) that full
+// enumeration would explode.
+//
+// hybrid, if set, is applied the same way as in SubstituteParameters: any
+// candidate whose own SimilarityScore is below hybrid.MinScore is dropped
+// before search, not just the best one.
+//
+// Each concept's ParameterSpec (if its placeholder declared one) is honored
+// the same way SubstituteParameters honors it: a pattern-typed parameter
+// drops candidates whose variable name doesn't satisfy the pattern, an
+// optional parameter falls back to its Default (as a single neutrally-scored
+// candidate) instead of failing the whole template when nothing survives,
+// and only a multi-value parameter's placeholder widens the beam past that
+// concept's single best candidate - every other concept always resolves to
+// its top match, same as SubstituteParameters' greedy pick.
+//
+// candidatesPerConcept and topK fall back to DefaultCandidatesPerConcept and
+// DefaultAssignmentTopK when <= 0. The returned queries are sorted
+// best-first by Score; callers that only want the single best interpretation
+// can take index 0, callers that want to explore alternates can use the
+// whole slice.
+func ResolveTemplateAssignments(template *QueryTemplate, conceptMatches map[string][]types.ConceptMatch, hybrid *HybridMatchConfig, candidatesPerConcept, topK int) ([]*ParameterizedQuery, error) {
+	if candidatesPerConcept <= 0 {
+		candidatesPerConcept = DefaultCandidatesPerConcept
+	}
+	if topK <= 0 {
+		topK = DefaultAssignmentTopK
+	}
+
+	candidates := make(map[string][]types.ConceptMatch, len(template.Concepts))
+	for _, concept := range template.Concepts {
+		spec := template.Parameters[concept]
+		matches := conceptMatches[concept]
+
+		sorted := append([]types.ConceptMatch(nil), matches...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return sorted[i].SimilarityScore > sorted[j].SimilarityScore
+		})
+
+		filtered := sorted[:0]
+		for _, m := range sorted {
+			if matchPassesSpec(m, spec, hybrid) {
+				filtered = append(filtered, m)
+			}
+		}
+		sorted = filtered
+
+		if len(sorted) == 0 {
+			if spec != nil && spec.Optional {
+				// SimilarityScore 1 keeps the fallback neutral in the beam's
+				// multiplicative scoring (cf. the loop below): a parameter
+				// that had to fall back to its default shouldn't drag every
+				// assignment containing it down to 0.
+				sorted = []types.ConceptMatch{{Variable: types.VariableInfo{Name: spec.Default}, Concept: concept, SimilarityScore: 1}}
+			} else {
+				return nil, fmt.Errorf("no matches found for required concept: %s", concept)
+			}
+		}
+
+		// A non-multi parameter resolves to its single best candidate, the
+		// same as SubstituteParameters' greedy pick - only a ${name*}
+		// placeholder should widen the beam to explore alternatives.
+		if spec == nil || !spec.Multi {
+			sorted = sorted[:1]
+		} else if len(sorted) > candidatesPerConcept {
+			sorted = sorted[:candidatesPerConcept]
+		}
+		candidates[concept] = sorted
+	}
+
+	beam := []templateAssignment{{choices: make(map[string]types.ConceptMatch)}}
+	for _, concept := range template.Concepts {
+		var next []templateAssignment
+		for _, partial := range beam {
+			for _, cand := range candidates[concept] {
+				choices := make(map[string]types.ConceptMatch, len(partial.choices)+1)
+				for k, v := range partial.choices {
+					choices[k] = v
+				}
+				choices[concept] = cand
+
+				score := partial.score
+				if len(partial.choices) == 0 {
+					score = cand.SimilarityScore
+				} else {
+					score *= cand.SimilarityScore
+				}
+				score += coLocalityBonus(partial.choices, cand)
+
+				next = append(next, templateAssignment{
+					choices: choices,
+					score:   score,
+				})
+			}
+		}
+
+		sort.Slice(next, func(i, j int) bool { return next[i].score > next[j].score })
+		if len(next) > DefaultBeamWidth {
+			next = next[:DefaultBeamWidth]
+		}
+		beam = next
+	}
+
+	if len(beam) > topK {
+		beam = beam[:topK]
+	}
+
+	queries := make([]*ParameterizedQuery, len(beam))
+	for i, assignment := range beam {
+		queries[i] = substituteAssignment(template, assignment)
+	}
+	return queries, nil
+}
+
+// coLocalityBonus scores how well cand's variable sits alongside the
+// variables already chosen in existing: sameParentBonus per existing choice
+// sharing cand's ParentName, or a line-distance-decayed bonus otherwise.
+func coLocalityBonus(existing map[string]types.ConceptMatch, cand types.ConceptMatch) float32 {
+	var bonus float32
+	for _, other := range existing {
+		if cand.Variable.ParentName != "" && other.Variable.ParentName == cand.Variable.ParentName {
+			bonus += sameParentBonus
+			continue
+		}
+		dist := int(cand.Variable.LineNumber) - int(other.Variable.LineNumber)
+		if dist < 0 {
+			dist = -dist
+		}
+		bonus += lineProximityScale / float32(1+dist)
+	}
+	return bonus
+}
+
+// substituteAssignment builds the ParameterizedQuery for one completed
+// assignment, the same substitution SubstituteParameters does for its
+// single best-match assignment.
+func substituteAssignment(template *QueryTemplate, assignment templateAssignment) *ParameterizedQuery {
+	paramQuery := &ParameterizedQuery{
+		Template:   template,
+		Parameters: make(map[string]string, len(assignment.choices)),
+		Score:      assignment.score,
+	}
+
+	processedQuery := template.Original
+	for concept, match := range assignment.choices {
+		paramQuery.Parameters[concept] = match.Variable.Name
+		placeholder := fmt.Sprintf("${%s}", concept)
+		if spec := template.Parameters[concept]; spec != nil {
+			placeholder = spec.Raw
+		}
+		processedQuery = strings.ReplaceAll(processedQuery, placeholder, match.Variable.Name)
+	}
+	paramQuery.ProcessedQuery = processedQuery
+
+	return paramQuery
+}
+
+// ProcessTemplatedQueriesJoint is ProcessTemplatedQueries' joint-resolution
+// counterpart: for every template with at least one concept, it resolves
+// the top-K joint assignments via ResolveTemplateAssignments instead of one
+// greedy best match, and appends all of them (not just the best) to the
+// result. Downstream analyzers that want every plausible interpretation of
+// a template can consume the full result; ones that only want the best can
+// filter to the first query seen per template.
+func ProcessTemplatedQueriesJoint(queryTemplates map[string]*QueryTemplate, conceptMatches map[string][]types.ConceptMatch, hybrid *HybridMatchConfig, candidatesPerConcept, topK int) []*ParameterizedQuery {
+	var processed []*ParameterizedQuery
+
+	for _, template := range queryTemplates {
+		if len(template.Concepts) == 0 {
+			continue
+		}
+
+		queries, err := ResolveTemplateAssignments(template, conceptMatches, hybrid, candidatesPerConcept, topK)
+		if err != nil {
+			fmt.Printf("Warning: Skipping template %s: %v\n", template.Name, err)
+			continue
+		}
+
+		processed = append(processed, queries...)
+	}
+
+	return processed
+}
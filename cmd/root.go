@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/jeffrydegrande/solidair/pkg/config"
 	"github.com/spf13/cobra"
 )
 
@@ -15,9 +19,16 @@ patterns that led to the zkLend hack and other potential vulnerabilities. It use
 Tree-sitter to parse Cairo code and identify security issues through pattern matching.`,
 }
 
-// Execute adds all child commands to the root command and sets flags appropriately.
+// Execute adds all child commands to the root command and sets flags
+// appropriately. The context it runs rootCmd under is cancelled on the
+// first Ctrl-C (SIGINT) or SIGTERM, so a long-running command - analyzeCmd's
+// Scanner scanning a large directory, say - can stop its workers instead of
+// running to completion after the user's already asked it to quit.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := rootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
@@ -28,4 +39,14 @@ func init() {
 	rootCmd.PersistentFlags().StringP("query-dir", "q", "queries", "Directory containing query definitions")
 	rootCmd.PersistentFlags().StringP("api-key", "k", "", "OpenAI API key (can also be set via OPENAI_API_KEY env var)")
 	rootCmd.PersistentFlags().BoolP("offline", "o", false, "Run in offline mode without API calls")
-}
\ No newline at end of file
+	rootCmd.PersistentFlags().String("concepts-dir", "", "Directory of third-party concept pack *.toml files (also read from $SOLIDAIR_CONCEPTS_PATH and $XDG_CONFIG_HOME/solidair/concepts.d)")
+	rootCmd.PersistentFlags().String("config", config.DefaultFilename, "Path to a .solidair.toml config file for path/extension/finding exclusions and per-query overrides")
+}
+
+// loadConfig reads the --config file for cmd, returning an empty permissive
+// Config if it doesn't exist - so every command can unconditionally call
+// this and pass the result to ReadQueryFiles/RunQueries/filterFiles.
+func loadConfig(cmd *cobra.Command) (*config.Config, error) {
+	path, _ := cmd.Flags().GetString("config")
+	return config.Load(path)
+}
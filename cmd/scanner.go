@@ -0,0 +1,252 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/pkg/config"
+	"github.com/jeffrydegrande/solidair/taint"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// compiledQuery is one .scm file's pattern compiled once and reused across
+// every file its backend parses, instead of RunQueries' per-call
+// ReadQueryFiles+compile, which dominates cost scanning a large tree. A
+// Kind: taint query isn't compiled here at all - it's kept as a taint.Query
+// and run through taint.Run, like RunQueries does for a single file.
+type compiledQuery struct {
+	name        string
+	file        string
+	description string
+	severity    string
+	query       *tree_sitter.Query // nil for a taint query
+	taintQuery  *taint.Query       // set only for a taint query
+}
+
+// Scanner walks a list of mixed-language source files across a pool of
+// worker goroutines, compiling each backend's queries once on first use and
+// reusing a per-backend pool of QueryCursors (a tree-sitter QueryCursor, like
+// its Parser, isn't safe to share across goroutines) for the rest of the
+// run. It mirrors scanner.Scanner.ScanDir's worker-pool shape - the legacy
+// Cairo-only CLI's answer to the same per-file recompilation cost -
+// generalized across languages.Backend and config.Config's exclusion and
+// severity-override rules.
+type Scanner struct {
+	queryDir string
+	cfg      *config.Config
+	workers  int
+
+	mu       sync.Mutex
+	compiled map[string][]*compiledQuery // keyed by backend.Name(), compiled lazily
+
+	cursors sync.Map // backend.Name() -> *sync.Pool of *tree_sitter.QueryCursor
+}
+
+// NewScanner returns a Scanner that loads queries from queryDir, filtered
+// and overridden by cfg, scanning with workers goroutines. workers <= 0
+// defaults to runtime.NumCPU().
+func NewScanner(queryDir string, cfg *config.Config, workers int) *Scanner {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &Scanner{
+		queryDir: queryDir,
+		cfg:      cfg,
+		workers:  workers,
+		compiled: make(map[string][]*compiledQuery),
+	}
+}
+
+// ScanFiles fans files out across the Scanner's workers, each parsing a file
+// with its own backend.Parse call and matching it against that backend's
+// compiled queries, and streams every result onto the returned channel as
+// it's found. Both channels are closed once every file has been scanned or
+// ctx is cancelled (e.g. by Ctrl-C reaching the context Execute builds);
+// a file that fails to read, parse, or load queries for is reported on the
+// error channel rather than aborting the rest of the scan.
+func (s *Scanner) ScanFiles(ctx context.Context, files []string) (<-chan fileQueryResult, <-chan error) {
+	results := make(chan fileQueryResult)
+	errs := make(chan error, s.workers)
+	paths := make(chan string)
+
+	var wg sync.WaitGroup
+	wg.Add(s.workers)
+	for i := 0; i < s.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				fileResults, err := s.scanFile(path)
+				if err != nil {
+					select {
+					case errs <- err:
+					case <-ctx.Done():
+					}
+					continue
+				}
+
+				for _, r := range fileResults {
+					select {
+					case results <- fileQueryResult{FilePath: path, Result: r}:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(paths)
+		for _, f := range files {
+			select {
+			case paths <- f:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+		close(errs)
+	}()
+
+	return results, errs
+}
+
+// scanFile parses one file with its registered languages.Backend and runs
+// every one of that backend's compiled queries (and, for any Kind: taint
+// query, taint.Run) against it.
+func (s *Scanner) scanFile(path string) ([]QueryResult, error) {
+	backend, ok := languages.ForPath(path)
+	if !ok {
+		return nil, fmt.Errorf("no language backend registered for %s", path)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading file %s: %w", path, err)
+	}
+
+	tree, err := backend.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s: %w", path, err)
+	}
+	defer tree.Close()
+
+	cqs, err := s.queriesFor(backend)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s queries for %s: %w", backend.Name(), path, err)
+	}
+
+	pool := s.cursorPool(backend)
+	root := tree.RootNode()
+
+	var results []QueryResult
+	var taintQueries []taint.Query
+	for _, cq := range cqs {
+		if cq.taintQuery != nil {
+			taintQueries = append(taintQueries, *cq.taintQuery)
+			continue
+		}
+
+		qc := pool.Get().(*tree_sitter.QueryCursor)
+		results = append(results, collectMatches(qc, cq.query, root, data, cq.name, cq.file, cq.description, cq.severity, s.cfg)...)
+		pool.Put(qc)
+	}
+
+	if len(taintQueries) > 0 {
+		taintFindings, err := taint.Run(data, tree, backend, taintQueries)
+		if err != nil {
+			return nil, fmt.Errorf("error running taint analysis on %s: %w", path, err)
+		}
+		for _, tf := range taintFindings {
+			if s.cfg.SkipCode(tf.Code) {
+				continue
+			}
+			results = append(results, queryResultFromTaintFinding(tf))
+		}
+	}
+
+	return results, nil
+}
+
+// queriesFor returns backend's compiled query set, compiling it from disk
+// the first time backend.Name() is requested and serving every later file
+// of that backend from memory - the fix for RunQueries' per-call
+// recompilation this type exists for.
+func (s *Scanner) queriesFor(backend languages.Backend) ([]*compiledQuery, error) {
+	s.mu.Lock()
+	cqs, ok := s.compiled[backend.Name()]
+	s.mu.Unlock()
+	if ok {
+		return cqs, nil
+	}
+
+	raw, err := ReadQueryFiles(s.queryDir, backend, s.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	lang := backend.TSLanguage()
+	var compiled []*compiledQuery
+	for file, content := range raw {
+		name, description, querySeverity, kind := ExtractQueryMetadata(content)
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		}
+		if !s.cfg.QueryEnabled(name) {
+			continue
+		}
+		severity := querySeverity
+		if override, ok := s.cfg.SeverityOverride(name); ok {
+			severity = override
+		}
+
+		pattern := extractQueryPattern(content)
+
+		if kind == "taint" {
+			compiled = append(compiled, &compiledQuery{
+				name: name, file: file, description: description, severity: severity,
+				taintQuery: &taint.Query{Name: name, File: file, Description: description, Severity: severity, Pattern: pattern},
+			})
+			continue
+		}
+
+		query, err := tree_sitter.NewQuery(lang, pattern)
+		if err != nil {
+			fmt.Printf("Error compiling query %s: %v\n", file, err)
+			continue
+		}
+		compiled = append(compiled, &compiledQuery{name: name, file: file, description: description, severity: severity, query: query})
+	}
+
+	s.mu.Lock()
+	s.compiled[backend.Name()] = compiled
+	s.mu.Unlock()
+	return compiled, nil
+}
+
+// cursorPool returns backend's QueryCursor pool, creating it the first time
+// backend.Name() is requested.
+func (s *Scanner) cursorPool(backend languages.Backend) *sync.Pool {
+	if p, ok := s.cursors.Load(backend.Name()); ok {
+		return p.(*sync.Pool)
+	}
+	pool := &sync.Pool{New: func() any { return tree_sitter.NewQueryCursor() }}
+	actual, _ := s.cursors.LoadOrStore(backend.Name(), pool)
+	return actual.(*sync.Pool)
+}
@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"unsafe"
+
+	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/pkg/project"
+	"github.com/spf13/cobra"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// searchProviderFlag, searchModelFlag, and searchHostFlag select and
+// configure the EmbeddingsProvider used to index and query (ignored in
+// --offline mode), mirroring matchCmd's flags for cmd's legacy
+// EmbeddingsProvider interface rather than the newer embedding package.
+var (
+	searchProviderFlag string
+	searchModelFlag    string
+	searchHostFlag     string
+)
+
+// searchCacheDirFlag is the directory for the persisted chunk index,
+// defaulting to DefaultCacheDir().
+var searchCacheDirFlag string
+
+// searchTopKFlag is how many spans searchCmd prints.
+var searchTopKFlag int
+
+var searchCmd = &cobra.Command{
+	Use:   "search [file|dir] <query>",
+	Short: "Semantically search a Cairo project's functions, storage, and events",
+	Long: `Indexes every function, the contract's Storage struct, and #[event] enum under
+[file|dir] as a SemanticChunk, embedding any that are new or changed since the last
+run, then ranks every indexed chunk against <query> by cosine similarity and prints
+the top matches as file:line spans. This turns matching from "is this variable named
+like a security concept" into a general natural-language search over what the code
+does.`,
+	Args: cobra.ExactArgs(2),
+	Run:  searchMain,
+}
+
+func init() {
+	searchCmd.Flags().StringVar(&searchProviderFlag, "provider", "openai", "embeddings provider: openai, ollama, or local (ignored with --offline)")
+	searchCmd.Flags().StringVar(&searchModelFlag, "model", "", "model name passed to the provider (ollama only; openai always uses ada-002)")
+	searchCmd.Flags().StringVar(&searchHostFlag, "host", "", "server host for the ollama provider (defaults to the provider's own default)")
+	searchCmd.Flags().StringVar(&searchCacheDirFlag, "cache-dir", "", "directory for the persistent chunk index (defaults to DefaultCacheDir())")
+	searchCmd.Flags().IntVar(&searchTopKFlag, "top-k", 10, "number of spans to return")
+	rootCmd.AddCommand(searchCmd)
+}
+
+func searchMain(cmd *cobra.Command, args []string) {
+	path, query := args[0], args[1]
+
+	provider, err := buildSearchProvider(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cacheDir := searchCacheDirFlag
+	if cacheDir == "" {
+		cacheDir = DefaultCacheDir()
+	}
+	diskCache, err := OpenDiskEmbeddingCache(cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening chunk index: %v\n", err)
+		os.Exit(1)
+	}
+	defer diskCache.Close()
+
+	index := NewSemanticIndex(provider, diskCache)
+
+	files, err := project.DiscoverFiles(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error discovering %s: %v\n", path, err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	var embedded, total int
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", file, err)
+			os.Exit(1)
+		}
+
+		parser := tree_sitter.NewParser()
+		if err := parser.SetLanguage(tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))); err != nil {
+			parser.Close()
+			fmt.Fprintf(os.Stderr, "Error setting language: %v\n", err)
+			os.Exit(1)
+		}
+		tree := parser.Parse(data, nil)
+
+		fileEmbedded, fileTotal, err := index.IndexFile(ctx, file, data, tree)
+		tree.Close()
+		parser.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error indexing %s: %v\n", file, err)
+			os.Exit(1)
+		}
+		embedded += fileEmbedded
+		total += fileTotal
+	}
+	fmt.Printf("Indexed %d spans (%d newly embedded) across %d files\n\n", total, embedded, len(files))
+
+	results, err := index.Search(ctx, query, searchTopKFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error searching: %v\n", err)
+		os.Exit(1)
+	}
+
+	if len(results) == 0 {
+		fmt.Println("No matches found.")
+		return
+	}
+
+	for _, r := range results {
+		fmt.Printf("%s:%d-%d  %s %s  (score %.4f)\n", r.Chunk.FilePath, r.Chunk.StartLine, r.Chunk.EndLine, r.Chunk.Kind, r.Chunk.Name, r.Score)
+	}
+}
+
+// buildSearchProvider constructs an EmbeddingsProvider from the --offline/
+// --provider/--model/--host/--api-key flags, the selection buildMatcher
+// makes for the newer embedding package, but for cmd's legacy
+// EmbeddingsProvider interface.
+func buildSearchProvider(cmd *cobra.Command) (EmbeddingsProvider, error) {
+	offline, _ := cmd.Flags().GetBool("offline")
+	if offline {
+		return NewLocalHashEmbeddingsProvider(), nil
+	}
+
+	switch searchProviderFlag {
+	case "", "openai":
+		apiKey, _ := cmd.Flags().GetString("api-key")
+		if apiKey == "" {
+			apiKey = GetAPIKey()
+		}
+		if apiKey == "" {
+			return nil, fmt.Errorf("openai provider requires an API key (--api-key or OPENAI_API_KEY, or use --offline)")
+		}
+		return NewOpenAIClient(apiKey), nil
+	case "ollama":
+		return NewOllamaEmbeddingsProvider(searchHostFlag, searchModelFlag), nil
+	case "local":
+		return NewLocalHashEmbeddingsProvider(), nil
+	default:
+		return nil, fmt.Errorf("unknown provider %q (want openai, ollama, or local)", searchProviderFlag)
+	}
+}
@@ -0,0 +1,250 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/pkg/project"
+	"github.com/spf13/cobra"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// searchWatchDebounceFlag and searchWatchWorkersFlag control how
+// searchWatchCmd batches up bursts of writes and how many files it
+// re-indexes at once.
+var (
+	searchWatchDebounceFlag time.Duration
+	searchWatchWorkersFlag  int
+)
+
+var searchWatchCmd = &cobra.Command{
+	Use:   "watch [dir]",
+	Short: "Keep the chunk index up to date as Cairo files change",
+	Long: `Watch observes dir for changes to .cairo files the same way the top-level watch
+command does, but re-indexes into the chunked SemanticIndex search uses
+instead of re-running query/concept analysis. Bursts of writes to the same
+file are debounced (500ms by default) before it's re-indexed, and changed
+files are re-indexed on a pool of background workers, each feeding its file
+through the same batching queue IndexFile uses, so several files changing
+at once don't serialize behind each other. Re-indexing a file replaces its
+chunks atomically, so a concurrent "solidair search" never sees only part
+of a file's spans updated. Progress is written to stderr as
+"status queued=N embedded=N hits=N" lines so editors/CI can parse it
+without scraping human-readable output.`,
+	Args: cobra.ExactArgs(1),
+	Run:  searchWatchMain,
+}
+
+func init() {
+	searchWatchCmd.Flags().DurationVar(&searchWatchDebounceFlag, "debounce", 500*time.Millisecond, "how long to wait after a file's last write before re-indexing it")
+	searchWatchCmd.Flags().IntVar(&searchWatchWorkersFlag, "workers", 4, "number of files to re-index concurrently")
+	searchCmd.AddCommand(searchWatchCmd)
+}
+
+// searchWatchStatus tracks the counters searchWatchMain reports on stderr:
+// how many files are queued awaiting re-index, and how many chunks have
+// been newly embedded vs. served from the cache so far this run.
+type searchWatchStatus struct {
+	mu       sync.Mutex
+	queued   int
+	embedded int
+	hits     int
+}
+
+// report applies a delta to each counter and writes the new totals to
+// stderr as a single status line.
+func (s *searchWatchStatus) report(deltaQueued, deltaEmbedded, deltaHits int) {
+	s.mu.Lock()
+	s.queued += deltaQueued
+	s.embedded += deltaEmbedded
+	s.hits += deltaHits
+	queued, embedded, hits := s.queued, s.embedded, s.hits
+	s.mu.Unlock()
+	fmt.Fprintf(os.Stderr, "status queued=%d embedded=%d hits=%d\n", queued, embedded, hits)
+}
+
+func searchWatchMain(cmd *cobra.Command, args []string) {
+	dir := args[0]
+
+	provider, err := buildSearchProvider(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cacheDir := searchCacheDirFlag
+	if cacheDir == "" {
+		cacheDir = DefaultCacheDir()
+	}
+	diskCache, err := OpenDiskEmbeddingCache(cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening chunk index: %v\n", err)
+		os.Exit(1)
+	}
+	defer diskCache.Close()
+
+	index := NewSemanticIndex(provider, diskCache)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating watcher: %v\n", err)
+		os.Exit(1)
+	}
+	defer watcher.Close()
+
+	if err := watchDirs(watcher, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error watching %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	status := &searchWatchStatus{}
+
+	workers := searchWatchWorkersFlag
+	if workers < 1 {
+		workers = 1
+	}
+	jobs := make(chan string, 256)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range jobs {
+				reindexFile(context.Background(), index, path, status)
+			}
+		}()
+	}
+
+	files, err := project.DiscoverFiles(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error discovering %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+	status.report(len(files), 0, 0)
+	for _, f := range files {
+		jobs <- f
+	}
+
+	fmt.Printf("Watching %s for changes to .cairo files, re-indexing for search (Ctrl-C to stop)...\n\n", dir)
+
+	debouncer := newFileDebouncer(searchWatchDebounceFlag, func(path string) {
+		status.report(1, 0, 0)
+		jobs <- path
+	})
+	defer debouncer.stop()
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				close(jobs)
+				wg.Wait()
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watchDirs(watcher, event.Name)
+					continue
+				}
+			}
+			if !strings.HasSuffix(event.Name, ".cairo") {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			debouncer.trigger(event.Name)
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Watcher error: %v\n", watchErr)
+		}
+	}
+}
+
+// reindexFile parses path fresh and re-indexes it into index via
+// IndexFileAtomic, reporting the result on status. Unlike watchCmd's
+// rescanFile, there's no previous tree to reuse here: each worker only
+// handles one file at a time and files change independently of each other,
+// so there's no shared incremental-parse state worth keeping across
+// reindexes.
+func reindexFile(ctx context.Context, index *SemanticIndex, path string, status *searchWatchStatus) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+		status.report(-1, 0, 0)
+		return
+	}
+
+	parser := tree_sitter.NewParser()
+	if err := parser.SetLanguage(tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))); err != nil {
+		parser.Close()
+		fmt.Fprintf(os.Stderr, "Error setting language: %v\n", err)
+		status.report(-1, 0, 0)
+		return
+	}
+	tree := parser.Parse(source, nil)
+
+	embedded, hits, _, err := index.IndexFileAtomic(ctx, path, source, tree)
+	tree.Close()
+	parser.Close()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error indexing %s: %v\n", path, err)
+		status.report(-1, 0, 0)
+		return
+	}
+
+	status.report(-1, embedded, hits)
+}
+
+// fileDebouncer coalesces bursts of fsnotify events for the same path into
+// a single call to its fire function, delayed until wait has passed since
+// that path's most recent event - the same problem an editor creates by
+// saving a file as several quick writes in a row.
+type fileDebouncer struct {
+	wait time.Duration
+	fire func(path string)
+
+	mu     sync.Mutex
+	timers map[string]*time.Timer
+}
+
+// newFileDebouncer creates a debouncer that calls fire(path) wait after
+// trigger(path)'s most recent call for that path.
+func newFileDebouncer(wait time.Duration, fire func(path string)) *fileDebouncer {
+	return &fileDebouncer{wait: wait, fire: fire, timers: make(map[string]*time.Timer)}
+}
+
+// trigger resets path's debounce timer, canceling any pending fire for it
+// that hasn't gone off yet.
+func (d *fileDebouncer) trigger(path string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if t, ok := d.timers[path]; ok {
+		t.Stop()
+	}
+	d.timers[path] = time.AfterFunc(d.wait, func() {
+		d.mu.Lock()
+		delete(d.timers, path)
+		d.mu.Unlock()
+		d.fire(path)
+	})
+}
+
+// stop cancels every pending timer, so a debouncer being torn down doesn't
+// fire for a path after its caller has stopped listening.
+func (d *fileDebouncer) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, t := range d.timers {
+		t.Stop()
+	}
+}
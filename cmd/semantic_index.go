@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/jeffrydegrande/solidair/cairo"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// semanticSpanQuery captures the top-level item kinds SemanticIndex turns
+// into chunks: functions (including #[external] entry points), the
+// contract's Storage struct, and #[event] enum definitions.
+const semanticSpanQuery = `
+(function_item name: (identifier) @name) @function
+(struct_item name: (identifier) @name) @struct
+(enum_item name: (identifier) @name) @enum
+`
+
+// SemanticChunk is a semantically meaningful span of Cairo source - a
+// function, the Storage struct, or an event enum - together with enough
+// location info to embed it meaningfully and report a match back as a
+// file:line range. Unlike VariableInfo, which names a single identifier, a
+// chunk carries its whole declaration so a natural-language query like
+// "reentrancy guard" can match on what the code does, not just on an
+// identifier's name.
+type SemanticChunk struct {
+	FilePath  string
+	Kind      string // "function", "external", "storage", or "event"
+	Name      string
+	StartByte uint32
+	EndByte   uint32
+	StartLine uint32
+	EndLine   uint32
+	Code      string
+}
+
+// Header is the context line SemanticIndex prepends to a chunk's code
+// before embedding it, so the embedding reflects which file and language
+// the span came from and not just its bare text.
+func (c SemanticChunk) Header() string {
+	return fmt.Sprintf("file: %s\nlanguage: cairo\n", c.FilePath)
+}
+
+// Prompt is the text embedded for this chunk: Header() followed by its
+// source.
+func (c SemanticChunk) Prompt() string {
+	return c.Header() + c.Code
+}
+
+// ExtractSemanticChunks walks tree's top-level items and emits one
+// SemanticChunk per function, the Storage struct, and each #[event] enum,
+// tagging a function "external" instead of "function" when it's annotated
+// #[external]. Any other struct or enum is dropped: SemanticIndex only
+// cares about the spans a security reviewer would, not every declaration
+// in the file.
+func ExtractSemanticChunks(filePath string, source []byte, tree *tree_sitter.Tree) ([]SemanticChunk, error) {
+	lang := tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))
+	query, err := tree_sitter.NewQuery(lang, semanticSpanQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling semantic span query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, tree.RootNode(), source)
+
+	var chunks []SemanticChunk
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var item, nameNode *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			if names[capture.Index] == "name" {
+				nameNode = &node
+			} else {
+				item = &node
+			}
+		}
+		if item == nil {
+			continue
+		}
+
+		name := ""
+		if nameNode != nil {
+			name = string(source[nameNode.StartByte():nameNode.EndByte()])
+		}
+
+		kind := classifySpan(*item, name, source)
+		if kind == "" {
+			continue
+		}
+
+		chunks = append(chunks, SemanticChunk{
+			FilePath:  filePath,
+			Kind:      kind,
+			Name:      name,
+			StartByte: uint32(item.StartByte()),
+			EndByte:   uint32(item.EndByte()),
+			StartLine: uint32(item.StartPosition().Row) + 1,
+			EndLine:   uint32(item.EndPosition().Row) + 1,
+			Code:      string(source[item.StartByte():item.EndByte()]),
+		})
+	}
+
+	return chunks, nil
+}
+
+// classifySpan maps a captured function_item/struct_item/enum_item to the
+// SemanticChunk kind ExtractSemanticChunks emits, using its preceding
+// #[attribute]s (if any) to tell a #[external] entry point from a plain
+// function and an #[event] enum from any other one; an empty return tells
+// the caller to skip this item entirely.
+func classifySpan(item tree_sitter.Node, name string, source []byte) string {
+	switch item.Kind() {
+	case "function_item":
+		if strings.Contains(precedingAttributes(item, source), "external") {
+			return "external"
+		}
+		return "function"
+	case "struct_item":
+		if name == "Storage" {
+			return "storage"
+		}
+		return ""
+	case "enum_item":
+		if strings.Contains(precedingAttributes(item, source), "event") {
+			return "event"
+		}
+		return ""
+	default:
+		return ""
+	}
+}
+
+// precedingAttributes concatenates the text of every #[...] attribute_item
+// immediately preceding item, so classifySpan can check for #[external] or
+// #[event] without caring how many attributes are stacked above a span.
+func precedingAttributes(item tree_sitter.Node, source []byte) string {
+	var b strings.Builder
+	for sibling := item.PrevNamedSibling(); sibling != nil && sibling.Kind() == "attribute_item"; sibling = sibling.PrevNamedSibling() {
+		b.WriteString(string(source[sibling.StartByte():sibling.EndByte()]))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// truncateChunkPrompt shortens prompt's code to fit under maxTokens,
+// estimated the same way embeddingQueue sizes batches - one token per four
+// characters - so a single large function body is still embeddable instead
+// of being rejected outright by the provider's own hard limit.
+func truncateChunkPrompt(prompt string, maxTokens int) string {
+	if maxTokens <= 0 || estimateQueueTokens(prompt) <= maxTokens {
+		return prompt
+	}
+	const truncatedSuffix = "\n... (truncated)"
+	maxChars := maxTokens*4 - len(truncatedSuffix)
+	if maxChars < 0 {
+		maxChars = 0
+	}
+	if maxChars >= len(prompt) {
+		return prompt
+	}
+	return prompt[:maxChars] + truncatedSuffix
+}
+
+// SemanticIndex builds and queries a persisted, content-addressed index of
+// SemanticChunk embeddings: the chunk-level analog of DiskEmbeddingCache.
+// Where DiskEmbeddingCache dedupes identical variable-name texts,
+// SemanticIndex tracks one row per (file, byte range), so an unchanged span
+// reuses its stored vector across runs and a query can be scored against
+// every indexed chunk without re-embedding anything.
+type SemanticIndex struct {
+	Provider EmbeddingsProvider
+	Cache    *DiskEmbeddingCache
+}
+
+// NewSemanticIndex creates a SemanticIndex backed by provider and persisted
+// through cache.
+func NewSemanticIndex(provider EmbeddingsProvider, cache *DiskEmbeddingCache) *SemanticIndex {
+	return &SemanticIndex{Provider: provider, Cache: cache}
+}
+
+// IndexFile extracts every SemanticChunk from source and embeds the ones
+// whose content has changed (or weren't indexed before), batching calls to
+// i.Provider through an embeddingQueue and reusing i.Cache's stored vector
+// for every chunk whose digest is unchanged. It returns how many chunks
+// were newly embedded out of the total found, so a caller can report
+// progress the way embeddingsRebuildMain does for the concept index.
+func (i *SemanticIndex) IndexFile(ctx context.Context, filePath string, source []byte, tree *tree_sitter.Tree) (embedded, total int, err error) {
+	chunks, err := ExtractSemanticChunks(filePath, source, tree)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	queue := newEmbeddingQueue(i.Provider)
+
+	type pendingChunk struct {
+		chunk  SemanticChunk
+		digest string
+		future <-chan queuedResult
+	}
+	var pending []pendingChunk
+
+	for _, chunk := range chunks {
+		prompt := truncateChunkPrompt(chunk.Prompt(), i.Provider.MaxTokens())
+		digest := cacheDigest(i.Provider.Name(), prompt)
+
+		if existing, ok, err := i.Cache.ChunkDigest(i.Provider.Name(), chunk.FilePath, chunk.StartByte, chunk.EndByte); err == nil && ok && existing == digest {
+			continue
+		}
+
+		pending = append(pending, pendingChunk{chunk: chunk, digest: digest, future: queue.enqueue(prompt)})
+	}
+
+	if len(pending) == 0 {
+		return 0, len(chunks), nil
+	}
+
+	queue.flush(ctx)
+
+	for _, p := range pending {
+		res := <-p.future
+		if res.err != nil {
+			return embedded, len(chunks), res.err
+		}
+		if err := i.Cache.PutChunk(i.Provider.Name(), p.chunk, p.digest, res.embedding); err != nil {
+			return embedded, len(chunks), err
+		}
+		embedded++
+	}
+
+	return embedded, len(chunks), nil
+}
+
+// IndexFileAtomic behaves like IndexFile, but writes filePath's whole chunk
+// set back through i.Cache.ReplaceFileChunks in one transaction instead of
+// upserting each chunk as it's embedded, and also reports cache hits
+// alongside embedded/total. It's searchWatchCmd's entry point into
+// SemanticIndex rather than IndexFile's: a background re-index must not let
+// a concurrent Search see half of a file's old spans and half of its new
+// ones, and a span removed from the file since the last index (a deleted
+// function) needs to disappear rather than linger as a stale row.
+func (i *SemanticIndex) IndexFileAtomic(ctx context.Context, filePath string, source []byte, tree *tree_sitter.Tree) (embedded, hits, total int, err error) {
+	chunks, err := ExtractSemanticChunks(filePath, source, tree)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	queue := newEmbeddingQueue(i.Provider)
+
+	type pendingChunk struct {
+		chunk  SemanticChunk
+		digest string
+		future <-chan queuedResult
+	}
+	var pending []pendingChunk
+	var entries []chunkEntry
+
+	for _, chunk := range chunks {
+		prompt := truncateChunkPrompt(chunk.Prompt(), i.Provider.MaxTokens())
+		digest := cacheDigest(i.Provider.Name(), prompt)
+
+		if existingDigest, existingEmbedding, ok, cerr := i.Cache.ChunkEntry(i.Provider.Name(), chunk.FilePath, chunk.StartByte, chunk.EndByte); cerr == nil && ok && existingDigest == digest {
+			entries = append(entries, chunkEntry{chunk: chunk, digest: digest, embedding: existingEmbedding})
+			hits++
+			continue
+		}
+
+		pending = append(pending, pendingChunk{chunk: chunk, digest: digest, future: queue.enqueue(prompt)})
+	}
+
+	if len(pending) > 0 {
+		queue.flush(ctx)
+
+		for _, p := range pending {
+			res := <-p.future
+			if res.err != nil {
+				return embedded, hits, len(chunks), res.err
+			}
+			entries = append(entries, chunkEntry{chunk: p.chunk, digest: p.digest, embedding: res.embedding})
+			embedded++
+		}
+	}
+
+	if err := i.Cache.ReplaceFileChunks(i.Provider.Name(), filePath, entries); err != nil {
+		return embedded, hits, len(chunks), err
+	}
+
+	return embedded, hits, len(chunks), nil
+}
+
+// SemanticSearchResult is one chunk SemanticIndex.Search ranked against a
+// query, with enough location info for a caller to report file:line.
+type SemanticSearchResult struct {
+	Chunk StoredChunk
+	Score float32
+}
+
+// Search embeds query and returns the topK indexed chunks by cosine
+// similarity, highest first.
+func (i *SemanticIndex) Search(ctx context.Context, query string, topK int) ([]SemanticSearchResult, error) {
+	embeddings, err := i.Provider.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, fmt.Errorf("error embedding query: %w", err)
+	}
+	queryEmbedding := embeddings[0]
+
+	stored, err := i.Cache.Chunks(i.Provider.Name())
+	if err != nil {
+		return nil, fmt.Errorf("error reading indexed chunks: %w", err)
+	}
+
+	results := make([]SemanticSearchResult, 0, len(stored))
+	for _, chunk := range stored {
+		results = append(results, SemanticSearchResult{
+			Chunk: chunk,
+			Score: CosineSimilarity(queryEmbedding, chunk.Embedding),
+		})
+	}
+	sort.Slice(results, func(a, b int) bool { return results[a].Score > results[b].Score })
+	if topK > 0 && len(results) > topK {
+		results = results[:topK]
+	}
+	return results, nil
+}
@@ -4,44 +4,118 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+
+	"github.com/jeffrydegrande/solidair/types"
 )
 
 // QueryTemplate represents a Tree-sitter query with template parameters
 type QueryTemplate struct {
-	Name        string              // Query name
-	Description string              // Query description
-	Concepts    []string            // Required concepts
-	Source      string              // Source file
-	Original    string              // Original query string with templates
-	Parameters  map[string]struct{} // Template parameters
+	Name        string                    // Query name
+	Description string                    // Query description
+	Concepts    []string                  // Required concepts
+	Source      string                    // Source file
+	Original    string                    // Original query string with templates
+	Parameters  map[string]*ParameterSpec // Template parameters, keyed by name
 }
 
 // ParameterizedQuery represents a query with actual parameter values
 type ParameterizedQuery struct {
-	Template        *QueryTemplate    // Original template
-	Parameters      map[string]string // Parameter values
-	ProcessedQuery  string            // Query with parameters substituted
+	Template       *QueryTemplate    // Original template
+	Parameters     map[string]string // Parameter values
+	ProcessedQuery string            // Query with parameters substituted
+	// Score is the joint assignment score from ResolveTemplateAssignments:
+	// the product of each parameter's match similarity plus a co-locality
+	// bonus. Zero on queries produced by SubstituteParameters, which picks
+	// each parameter's best match independently rather than scoring a full
+	// assignment.
+	Score float32
+}
+
+// ParameterKind is the value type a ${name:kind} placeholder declares. The
+// zero value, ParamAny, is the original untyped ${name} form.
+type ParameterKind string
+
+const (
+	ParamAny        ParameterKind = ""
+	ParamIdentifier ParameterKind = "identifier"
+	ParamNumber     ParameterKind = "number"
+	ParamPattern    ParameterKind = "pattern"
+)
+
+// ParameterSpec is the parsed declaration behind one ${...} placeholder:
+// ${name}, ${name:kind}, ${name:pattern=/regex/}, ${name?=default}, or a
+// multi-value ${name*} / ${name:kind*}.
+type ParameterSpec struct {
+	Name     string         // parameter/concept name
+	Kind     ParameterKind  // declared type, or ParamAny if untyped
+	Pattern  *regexp.Regexp // set when Kind == ParamPattern
+	Optional bool           // true for ${name?=default} placeholders
+	Default  string         // substituted when Optional and no match is found
+	Multi    bool           // true for ${name*}: expand to one query per candidate match
+	Raw      string         // exact placeholder text in Original, e.g. "${admin:identifier}"
+	Line     int            // 1-based line within Original this placeholder occurs on
+}
+
+// TemplateValidationError is a single defect found by QueryTemplate.Validate.
+// Line is 0 when the defect isn't tied to one placeholder occurrence, such
+// as a Concepts entry with no matching placeholder anywhere in the query.
+type TemplateValidationError struct {
+	Line    int
+	Message string
+}
+
+func (e *TemplateValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
 }
 
-// ParseQueryTemplate parses a query string to extract template information
+// TemplateValidationErrors collects every defect Validate found in one pass,
+// so a caller sees the template's whole problem set instead of just the
+// first mismatch.
+type TemplateValidationErrors []*TemplateValidationError
+
+func (e TemplateValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// placeholderRegex matches the full template DSL: a name, an optional
+// ":kind" (with "=/regex/" for the pattern kind), an optional "*"
+// multi-value marker, and an optional "?=default" for optional parameters.
+var placeholderRegex = regexp.MustCompile(
+	`\$\{([a-zA-Z_][a-zA-Z0-9_]*)(?::([a-zA-Z]+)(?:=(/[^/]*/))?)?(\*)?(?:\?=([a-zA-Z0-9_]+))?\}`,
+)
+
+// ParseQueryTemplate parses a query string to extract template information,
+// including the typed/optional/multi-value parameter DSL described on
+// ParameterSpec. Malformed declarations (an unknown kind, a pattern that
+// doesn't compile, a name redeclared with a conflicting type) fail parsing
+// immediately. Structural problems that need the full Concepts list to
+// detect - a placeholder with no matching concept, or vice versa - are left
+// for Validate, which callers should run right after parsing.
 func ParseQueryTemplate(queryContent, source string) (*QueryTemplate, error) {
 	template := &QueryTemplate{
-		Parameters: make(map[string]struct{}),
+		Parameters: make(map[string]*ParameterSpec),
 		Source:     source,
 		Original:   queryContent,
 	}
-	
+
 	// Extract metadata from comments
 	nameRegex := regexp.MustCompile(`(?m)^;\s*Name:\s*(.+)$`)
 	if matches := nameRegex.FindStringSubmatch(queryContent); len(matches) > 1 {
 		template.Name = matches[1]
 	}
-	
+
 	descRegex := regexp.MustCompile(`(?m)^;\s*Description:\s*(.+)$`)
 	if matches := descRegex.FindStringSubmatch(queryContent); len(matches) > 1 {
 		template.Description = matches[1]
 	}
-	
+
 	// Extract concepts
 	conceptsRegex := regexp.MustCompile(`(?m)^;\s*Concepts:\s*(.+)$`)
 	if matches := conceptsRegex.FindStringSubmatch(queryContent); len(matches) > 1 {
@@ -52,77 +126,297 @@ func ParseQueryTemplate(queryContent, source string) (*QueryTemplate, error) {
 		}
 		template.Concepts = concepts
 	}
-	
+
 	// Extract template parameters
-	paramRegex := regexp.MustCompile(`\${([a-zA-Z_][a-zA-Z0-9_]*)}`)
-	matches := paramRegex.FindAllStringSubmatch(queryContent, -1)
-	
-	for _, match := range matches {
-		if len(match) >= 2 {
-			paramName := match[1]
-			template.Parameters[paramName] = struct{}{}
+	for _, loc := range placeholderRegex.FindAllStringSubmatchIndex(queryContent, -1) {
+		raw := queryContent[loc[0]:loc[1]]
+		name := submatch(queryContent, loc, 1)
+		kindStr := submatch(queryContent, loc, 2)
+		kindValue := submatch(queryContent, loc, 3)
+		multi := submatch(queryContent, loc, 4) == "*"
+		defaultVal := submatch(queryContent, loc, 5)
+		line := 1 + strings.Count(queryContent[:loc[0]], "\n")
+
+		spec := &ParameterSpec{
+			Name:     name,
+			Kind:     ParameterKind(kindStr),
+			Multi:    multi,
+			Optional: defaultVal != "",
+			Default:  defaultVal,
+			Raw:      raw,
+			Line:     line,
 		}
+
+		switch spec.Kind {
+		case ParamAny, ParamIdentifier, ParamNumber:
+			// No extra validation needed at parse time.
+		case ParamPattern:
+			if kindValue == "" {
+				return nil, fmt.Errorf("line %d: ${%s:pattern} requires a =/regex/ value", line, name)
+			}
+			re, err := regexp.Compile(strings.TrimSuffix(strings.TrimPrefix(kindValue, "/"), "/"))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid pattern for ${%s}: %w", line, name, err)
+			}
+			spec.Pattern = re
+		default:
+			return nil, fmt.Errorf("line %d: unknown parameter type %q for ${%s}", line, kindStr, name)
+		}
+
+		if existing, ok := template.Parameters[name]; ok && !sameParameterSpec(existing, spec) {
+			return nil, fmt.Errorf("line %d: ${%s} redeclared with a conflicting type (first seen on line %d)", line, name, existing.Line)
+		}
+		template.Parameters[name] = spec
 	}
-	
+
 	return template, nil
 }
 
-// SubstituteParameters replaces template parameters with actual variable names
-func SubstituteParameters(template *QueryTemplate, conceptMatches map[string][]ConceptMatch) (*ParameterizedQuery, error) {
-	paramQuery := &ParameterizedQuery{
-		Template:   template,
-		Parameters: make(map[string]string),
+// submatch returns the regex submatch at group g from a match index slice
+// produced by FindAllStringSubmatchIndex, or "" if that group is optional
+// and didn't participate in the match.
+func submatch(s string, loc []int, g int) string {
+	start, end := loc[2*g], loc[2*g+1]
+	if start < 0 || end < 0 {
+		return ""
+	}
+	return s[start:end]
+}
+
+// sameParameterSpec reports whether two ParameterSpecs for the same name
+// declare the same type, defaulting, and multiplicity - i.e. whether a
+// second occurrence of ${name...} in the query is a harmless repeat rather
+// than a conflicting redeclaration. Raw and Line are expected to differ
+// between occurrences and are ignored.
+func sameParameterSpec(a, b *ParameterSpec) bool {
+	if a.Kind != b.Kind || a.Multi != b.Multi || a.Optional != b.Optional || a.Default != b.Default {
+		return false
+	}
+	if (a.Pattern == nil) != (b.Pattern == nil) {
+		return false
+	}
+	return a.Pattern == nil || a.Pattern.String() == b.Pattern.String()
+}
+
+// Validate checks that a template's parameter DSL and its Concepts list
+// agree: every ${...} placeholder must name a concept in Concepts, and
+// every concept in Concepts must be referenced by at least one placeholder.
+// It collects every mismatch instead of stopping at the first, so callers
+// (ProcessTemplatedQueries in particular) can report the whole problem
+// rather than a single silent skip.
+func (t *QueryTemplate) Validate() error {
+	var errs TemplateValidationErrors
+
+	conceptSet := make(map[string]bool, len(t.Concepts))
+	seen := make(map[string]bool, len(t.Concepts))
+	for _, c := range t.Concepts {
+		if seen[c] {
+			errs = append(errs, &TemplateValidationError{
+				Message: fmt.Sprintf("concept %q is listed more than once in Concepts", c),
+			})
+			continue
+		}
+		seen[c] = true
+		conceptSet[c] = true
+	}
+
+	used := make(map[string]bool, len(t.Parameters))
+	for name, spec := range t.Parameters {
+		used[name] = true
+		if !conceptSet[name] {
+			errs = append(errs, &TemplateValidationError{
+				Line:    spec.Line,
+				Message: fmt.Sprintf("${%s} has no corresponding entry in Concepts", name),
+			})
+		}
+	}
+
+	for _, c := range t.Concepts {
+		if !used[c] {
+			errs = append(errs, &TemplateValidationError{
+				Message: fmt.Sprintf("concept %q has no ${%s} placeholder in the query", c, c),
+			})
+		}
 	}
-	
-	processedQuery := template.Original
-	
-	// Check if we have matches for all required concepts
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// matchPassesSpec reports whether match is eligible to be bound to a
+// concept's placeholder: its SimilarityScore clears hybrid.MinScore (when
+// hybrid is set), and, for a pattern-typed parameter, its variable name
+// satisfies spec.Pattern. Shared by SubstituteParameters and
+// ResolveTemplateAssignments so the two entry points can't drift on what
+// counts as an eligible candidate.
+func matchPassesSpec(match types.ConceptMatch, spec *ParameterSpec, hybrid *HybridMatchConfig) bool {
+	if hybrid != nil && match.SimilarityScore < hybrid.MinScore {
+		return false
+	}
+	if spec != nil && spec.Pattern != nil && !spec.Pattern.MatchString(match.Variable.Name) {
+		return false
+	}
+	return true
+}
+
+// maxMultiExpansions caps how many ParameterizedQuery results
+// SubstituteParameters will produce for one template, so a template with
+// several multi-value parameters can't cross-product its way into
+// thousands of near-duplicate queries.
+const maxMultiExpansions = 50
+
+// SubstituteParameters replaces template parameters with actual variable
+// names, picking each concept's single best match - the greedy, independent
+// alternative to ResolveTemplateAssignments' joint search. hybrid is
+// optional (nil disables the check entirely); when set, its MinScore
+// rejects a match that cleared the matcher's own SimilarityThreshold but
+// scores below MinScore, so a weak fused match doesn't silently get
+// substituted into the query.
+//
+// A concept's ParameterSpec (if its placeholder declared one) changes how
+// its match is chosen: a pattern-typed parameter rejects candidates whose
+// variable name doesn't satisfy the pattern, an optional parameter falls
+// back to its Default instead of failing when no candidate survives, and a
+// multi-value parameter expands to one ParameterizedQuery per surviving
+// candidate instead of just the best one. When more than one concept is
+// multi-value, the result is their cross product, capped at
+// maxMultiExpansions.
+func SubstituteParameters(template *QueryTemplate, conceptMatches map[string][]types.ConceptMatch, hybrid *HybridMatchConfig) ([]*ParameterizedQuery, error) {
+	type resolvedConcept struct {
+		concept string
+		raw     string
+		values  []string
+	}
+
+	resolved := make([]resolvedConcept, 0, len(template.Concepts))
+
 	for _, concept := range template.Concepts {
-		matches, found := conceptMatches[concept]
-		if !found || len(matches) == 0 {
-			return nil, fmt.Errorf("no matches found for required concept: %s", concept)
-		}
-		
-		// Use the best match (highest similarity score)
-		bestMatch := matches[0]
-		paramName := concept
-		varName := bestMatch.Variable.Name
-		
-		// Store the parameter substitution
-		paramQuery.Parameters[paramName] = varName
-		
-		// Replace in the query string
-		placeholder := fmt.Sprintf("${%s}", paramName)
-		processedQuery = strings.ReplaceAll(processedQuery, placeholder, varName)
-	}
-	
-	// Store the processed query
-	paramQuery.ProcessedQuery = processedQuery
-	
-	return paramQuery, nil
+		spec := template.Parameters[concept]
+		raw := fmt.Sprintf("${%s}", concept)
+		if spec != nil {
+			raw = spec.Raw
+		}
+
+		var values []string
+		var bestScore float32
+		for _, match := range conceptMatches[concept] {
+			if !matchPassesSpec(match, spec, hybrid) {
+				continue
+			}
+			if spec == nil || !spec.Multi {
+				// single-value: keep the highest-scoring candidate that passes;
+				// conceptMatches lists a concept's matches in variable order,
+				// not by score.
+				if len(values) == 0 || match.SimilarityScore > bestScore {
+					values = []string{match.Variable.Name}
+					bestScore = match.SimilarityScore
+				}
+				continue
+			}
+			values = append(values, match.Variable.Name)
+		}
+
+		if len(values) == 0 {
+			if spec != nil && spec.Optional {
+				values = []string{spec.Default}
+			} else {
+				return nil, fmt.Errorf("no matches found for required concept: %s", concept)
+			}
+		}
+
+		resolved = append(resolved, resolvedConcept{concept: concept, raw: raw, values: values})
+	}
+
+	// Cross product across concepts' candidate value lists. Most templates
+	// have exactly one value per concept, so this is usually just the
+	// single greedy-best substitution the original implementation produced;
+	// only multi-value parameters expand it further.
+	combos := [][]string{{}}
+	for _, rc := range resolved {
+		var next [][]string
+		for _, combo := range combos {
+			for _, v := range rc.values {
+				next = append(next, append(append([]string(nil), combo...), v))
+				if len(next) >= maxMultiExpansions {
+					break
+				}
+			}
+			if len(next) >= maxMultiExpansions {
+				break
+			}
+		}
+		combos = next
+	}
+
+	queries := make([]*ParameterizedQuery, 0, len(combos))
+	for _, combo := range combos {
+		paramQuery := &ParameterizedQuery{
+			Template:   template,
+			Parameters: make(map[string]string, len(resolved)),
+		}
+
+		processedQuery := template.Original
+		for i, rc := range resolved {
+			paramQuery.Parameters[rc.concept] = combo[i]
+			processedQuery = strings.ReplaceAll(processedQuery, rc.raw, combo[i])
+		}
+		paramQuery.ProcessedQuery = processedQuery
+
+		queries = append(queries, paramQuery)
+	}
+
+	return queries, nil
 }
 
-// ProcessTemplatedQueries takes a set of query templates and processes them with matched variables
-func ProcessTemplatedQueries(queryTemplates map[string]*QueryTemplate, 
-                           conceptMatches map[string][]ConceptMatch) []*ParameterizedQuery {
+// TemplateProcessingError associates a processing failure - a Validate
+// error or a SubstituteParameters error - with the template it came from,
+// so a caller can report exactly which query file and line misbehaved
+// instead of the plain warning ProcessTemplatedQueries used to print.
+type TemplateProcessingError struct {
+	Source string // template.Source
+	Name   string // template.Name
+	Err    error  // a TemplateValidationErrors or a substitution error
+}
+
+func (e *TemplateProcessingError) Error() string {
+	return fmt.Sprintf("%s (%s): %v", e.Name, e.Source, e.Err)
+}
+
+func (e *TemplateProcessingError) Unwrap() error { return e.Err }
+
+// ProcessTemplatedQueries takes a set of query templates and processes them
+// with matched variables. hybrid is forwarded to SubstituteParameters
+// unchanged; pass nil to keep the original best-match-only behavior.
+//
+// Every template is validated before substitution; a template that fails
+// Validate or SubstituteParameters contributes a TemplateProcessingError to
+// the returned error slice instead of being silently skipped.
+func ProcessTemplatedQueries(queryTemplates map[string]*QueryTemplate,
+	conceptMatches map[string][]types.ConceptMatch, hybrid *HybridMatchConfig) ([]*ParameterizedQuery, []*TemplateProcessingError) {
 	var processed []*ParameterizedQuery
-	
+	var errs []*TemplateProcessingError
+
 	for _, template := range queryTemplates {
 		// Skip templates with no concepts
 		if len(template.Concepts) == 0 {
 			continue
 		}
-		
-		// Try to substitute parameters
-		paramQuery, err := SubstituteParameters(template, conceptMatches)
+
+		if err := template.Validate(); err != nil {
+			errs = append(errs, &TemplateProcessingError{Source: template.Source, Name: template.Name, Err: err})
+			continue
+		}
+
+		queries, err := SubstituteParameters(template, conceptMatches, hybrid)
 		if err != nil {
-			// Log the error but continue with other templates
-			fmt.Printf("Warning: Skipping template %s: %v\n", template.Name, err)
+			errs = append(errs, &TemplateProcessingError{Source: template.Source, Name: template.Name, Err: err})
 			continue
 		}
-		
-		processed = append(processed, paramQuery)
+
+		processed = append(processed, queries...)
 	}
-	
-	return processed
-}
\ No newline at end of file
+
+	return processed, errs
+}
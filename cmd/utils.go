@@ -7,9 +7,11 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"unsafe"
+	"sync"
 
-	cairo "github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/pkg/config"
+	"github.com/jeffrydegrande/solidair/taint"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
 
@@ -20,28 +22,66 @@ type QueryResult struct {
 	Description string
 	LineNumber  uint32
 	Code        string
+	// EndLine, Column, StartByte, and EndByte locate the match more
+	// precisely than LineNumber alone, recovered from the capture node's
+	// own start/end positions - report.SARIF uses them to build a SARIF
+	// region with startColumn/endLine and a byte-offset region.
+	EndLine   uint32
+	Column    uint32 // 1-based start column
+	StartByte uint32
+	EndByte   uint32
+	// Severity is cfg's per-query Queries override if one is set (see
+	// config.Config.SeverityOverride), else the query file's own Severity:
+	// metadata comment, else "".
+	Severity string
+	// EnclosingConstruct names the nearest function_item or struct_item the
+	// match is nested in, as "<kind>:<name>" (e.g. "function_item:withdraw"),
+	// or "" for a top-level match. It anchors a baseline.Fingerprint to
+	// where the code lives rather than its line number, so edits elsewhere
+	// in the file don't invalidate an already-baselined finding.
+	EnclosingConstruct string
 }
 
-// ReadQueryFiles reads all .scm files from the specified query directory
-func ReadQueryFiles(queryDir string) (map[string]string, error) {
+// ReadQueryFiles reads all .scm files for backend from queryDir/backend.Name().
+// If that per-language subdirectory doesn't exist and queryDir has no other
+// registered language's subdirectory either, queryDir itself is walked
+// instead, so a project that hasn't migrated to queries/<lang> subdirectories
+// at all still works. A project that HAS migrated but simply has no queries
+// for this backend yet gets an empty result rather than queryDir's walk
+// quietly pulling in a sibling language's .scm files and running them against
+// the wrong grammar. Results skip any whose relative path cfg excludes (see
+// config.Config.SkipPath). A nil cfg reads everything.
+func ReadQueryFiles(queryDir string, backend languages.Backend, cfg *config.Config) (map[string]string, error) {
 	queries := make(map[string]string)
 
-	err := filepath.WalkDir(queryDir, func(path string, d fs.DirEntry, err error) error {
+	dir := filepath.Join(queryDir, backend.Name())
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		if hasLanguageSubdir(queryDir) {
+			return queries, nil
+		}
+		dir = queryDir
+	}
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
 		// Only process .scm files
 		if !d.IsDir() && strings.HasSuffix(path, ".scm") {
-			queryContent, err := os.ReadFile(path)
+			// Use relative path from dir as the key
+			relPath, err := filepath.Rel(dir, path)
 			if err != nil {
-				return fmt.Errorf("error reading query file %s: %w", path, err)
+				return fmt.Errorf("error getting relative path for %s: %w", path, err)
+			}
+
+			if cfg.SkipPath(relPath) {
+				return nil
 			}
 
-			// Use relative path from queryDir as the key
-			relPath, err := filepath.Rel(queryDir, path)
+			queryContent, err := os.ReadFile(path)
 			if err != nil {
-				return fmt.Errorf("error getting relative path for %s: %w", path, err)
+				return fmt.Errorf("error reading query file %s: %w", path, err)
 			}
 
 			queries[relPath] = string(queryContent)
@@ -56,10 +96,80 @@ func ReadQueryFiles(queryDir string) (map[string]string, error) {
 	return queries, nil
 }
 
-// ExtractQueryMetadata parses the query file to extract metadata from comments
-func ExtractQueryMetadata(queryContent string) (string, string) {
+// hasLanguageSubdir reports whether queryDir contains a subdirectory named
+// after any registered backend, the signal that a project has migrated to
+// the queries/<lang> layout (as opposed to one that just hasn't adopted it
+// yet and keeps every query flat under queryDir).
+func hasLanguageSubdir(queryDir string) bool {
+	for _, name := range languages.Names() {
+		if info, err := os.Stat(filepath.Join(queryDir, name)); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// queryCache lazily loads and memoizes each language's query set from
+// queryDir, so a long-running command that rescans files over and over
+// (watchCmd, lspCmd) reads a given language's .scm files once no matter how
+// many of its files get scanned.
+type queryCache struct {
+	queryDir string
+	cfg      *config.Config
+
+	mu     sync.Mutex
+	byLang map[string]map[string]string
+}
+
+// newQueryCache returns a queryCache that loads queries from queryDir,
+// filtered by cfg, on first use per language.
+func newQueryCache(queryDir string, cfg *config.Config) *queryCache {
+	return &queryCache{
+		queryDir: queryDir,
+		cfg:      cfg,
+		byLang:   make(map[string]map[string]string),
+	}
+}
+
+// queriesFor returns backend's query set, reading it from disk the first
+// time backend.Name() is requested and serving every later request from
+// memory. The disk read itself happens outside the lock, so a worker
+// loading one language's queries for the first time doesn't block workers
+// already serving a different (or the same) language from cache; two
+// workers racing to load the same uncached language may both hit disk, but
+// only one result is kept, which is cheaper than serializing every cache hit
+// behind a single lock held for the walk's duration.
+func (c *queryCache) queriesFor(backend languages.Backend) (map[string]string, error) {
+	c.mu.Lock()
+	queries, ok := c.byLang[backend.Name()]
+	c.mu.Unlock()
+	if ok {
+		return queries, nil
+	}
+
+	queries, err := ReadQueryFiles(c.queryDir, backend, c.cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.byLang[backend.Name()] = queries
+	c.mu.Unlock()
+	return queries, nil
+}
+
+// ExtractQueryMetadata parses the query file to extract metadata from
+// comments: its Name:, Description:, Severity: (e.g. "high", used as the
+// default for report.SARIF's defaultConfiguration.level when cfg has no
+// override for this query - see config.Config.SeverityOverride), and Kind:.
+// Kind is "" for an ordinary pattern-match query, or "taint" for one
+// RunQueries hands off to the taint package instead of compiling and
+// matching itself - see taint.Query.
+func ExtractQueryMetadata(queryContent string) (string, string, string, string) {
 	description := ""
 	name := ""
+	severity := ""
+	kind := ""
 
 	// Extract description from comments
 	descRegex := regexp.MustCompile(`(?m)^;\s*Description:\s*(.+)$`)
@@ -73,26 +183,65 @@ func ExtractQueryMetadata(queryContent string) (string, string) {
 		name = matches[1]
 	}
 
-	return name, description
+	// Extract severity from comments
+	severityRegex := regexp.MustCompile(`(?m)^;\s*Severity:\s*(.+)$`)
+	if matches := severityRegex.FindStringSubmatch(queryContent); len(matches) > 1 {
+		severity = matches[1]
+	}
+
+	// Extract kind from comments
+	kindRegex := regexp.MustCompile(`(?m)^;\s*Kind:\s*(.+)$`)
+	if matches := kindRegex.FindStringSubmatch(queryContent); len(matches) > 1 {
+		kind = strings.TrimSpace(matches[1])
+	}
+
+	return name, description, severity, kind
 }
 
-// RunQueries executes all loaded queries against the source code
-func RunQueries(source []byte, tree *tree_sitter.Tree, queries map[string]string) ([]QueryResult, error) {
+// RunQueries executes all loaded queries against the source code using
+// backend's grammar, dropping disabled queries and results whose Code
+// matches cfg's blacklisted_strings, and stamping results with cfg's
+// per-query severity override. A nil cfg runs every query and filters
+// nothing. A query whose header comments parse as Kind: taint isn't
+// compiled and matched here at all - it's handed to taint.Run instead, and
+// its findings adapted back into QueryResult, since @source/@sink/@sanitizer
+// captures mean something taint.Run has to interpret, not a plain match.
+func RunQueries(source []byte, tree *tree_sitter.Tree, backend languages.Backend, queries map[string]string, cfg *config.Config) ([]QueryResult, error) {
 	var results []QueryResult
+	var taintQueries []taint.Query
 	root := tree.RootNode()
-	lang := tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))
+	lang := backend.TSLanguage()
 
 	for queryFile, queryContent := range queries {
 		// Extract metadata from the query file
-		queryName, description := ExtractQueryMetadata(queryContent)
+		queryName, description, querySeverity, kind := ExtractQueryMetadata(queryContent)
 		if queryName == "" {
 			// If no name is specified in the file, use the filename without extension
 			queryName = strings.TrimSuffix(filepath.Base(queryFile), filepath.Ext(queryFile))
 		}
 
+		if !cfg.QueryEnabled(queryName) {
+			continue
+		}
+		severity := querySeverity
+		if override, ok := cfg.SeverityOverride(queryName); ok {
+			severity = override
+		}
+
 		// Extract the actual query pattern (remove comments and metadata)
 		queryPattern := extractQueryPattern(queryContent)
 
+		if kind == "taint" {
+			taintQueries = append(taintQueries, taint.Query{
+				Name:        queryName,
+				File:        queryFile,
+				Description: description,
+				Severity:    severity,
+				Pattern:     queryPattern,
+			})
+			continue
+		}
+
 		// Compile the query
 		query, err := tree_sitter.NewQuery(lang, queryPattern)
 		if err != nil {
@@ -104,34 +253,103 @@ func RunQueries(source []byte, tree *tree_sitter.Tree, queries map[string]string
 		// Execute the query
 		qc := tree_sitter.NewQueryCursor()
 		defer qc.Close()
-		matches := qc.Matches(query, root, source)
-
-		// Process the matches
-		for match := matches.Next(); match != nil; match = matches.Next() {
-			for _, capture := range match.Captures {
-				node := capture.Node
-				text := string(source[node.StartByte():node.EndByte()])
-
-				// Get the line number for better reporting
-				startPosition := node.StartPosition()
-
-				results = append(results, QueryResult{
-					QueryName:   queryName,
-					QueryFile:   queryFile,
-					Description: description,
-					LineNumber:  uint32(startPosition.Row) + 1, // +1 because editors use 1-based line numbers
-					Code:        text,
-				})
-
-				// We only need one capture per match to report the issue
-				break
+		results = append(results, collectMatches(qc, query, root, source, queryName, queryFile, description, severity, cfg)...)
+	}
+
+	if len(taintQueries) > 0 {
+		taintFindings, err := taint.Run(source, tree, backend, taintQueries)
+		if err != nil {
+			return nil, fmt.Errorf("error running taint analysis: %w", err)
+		}
+		for _, tf := range taintFindings {
+			if cfg.SkipCode(tf.Code) {
+				continue
 			}
+			results = append(results, queryResultFromTaintFinding(tf))
 		}
 	}
 
 	return results, nil
 }
 
+// collectMatches runs query against root/source via qc, the same one-
+// capture-per-match reporting convention RunQueries always used, tagging
+// every result with the given query metadata and dropping any whose code
+// cfg blacklists. It's shared by RunQueries' per-call compile-and-run path
+// and Scanner's compile-once path, which only differ in where query and qc
+// come from.
+func collectMatches(qc *tree_sitter.QueryCursor, query *tree_sitter.Query, root *tree_sitter.Node, source []byte, queryName, queryFile, description, severity string, cfg *config.Config) []QueryResult {
+	var results []QueryResult
+	matches := qc.Matches(query, root, source)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		for _, capture := range match.Captures {
+			node := capture.Node
+			text := string(source[node.StartByte():node.EndByte()])
+
+			if cfg.SkipCode(text) {
+				break
+			}
+
+			startPosition := node.StartPosition()
+			endPosition := node.EndPosition()
+
+			results = append(results, QueryResult{
+				QueryName:          queryName,
+				QueryFile:          queryFile,
+				Description:        description,
+				LineNumber:         uint32(startPosition.Row) + 1, // +1 because editors use 1-based line numbers
+				EndLine:            uint32(endPosition.Row) + 1,
+				Column:             uint32(startPosition.Column) + 1,
+				StartByte:          uint32(node.StartByte()),
+				EndByte:            uint32(node.EndByte()),
+				Code:               text,
+				Severity:           severity,
+				EnclosingConstruct: enclosingConstruct(node, source),
+			})
+
+			// We only need one capture per match to report the issue
+			break
+		}
+	}
+	return results
+}
+
+// queryResultFromTaintFinding adapts a taint.Finding into a QueryResult,
+// the shape every other RunQueries result already takes, so a taint finding
+// flows through the same baseline/SARIF/JSON/text reporting unchanged.
+func queryResultFromTaintFinding(f taint.Finding) QueryResult {
+	return QueryResult{
+		QueryName:          f.QueryName,
+		QueryFile:          f.QueryFile,
+		Description:        f.Description,
+		LineNumber:         f.LineNumber,
+		EndLine:            f.EndLine,
+		Column:             f.Column,
+		StartByte:          f.StartByte,
+		EndByte:            f.EndByte,
+		Code:               f.Code,
+		Severity:           f.Severity,
+		EnclosingConstruct: f.EnclosingConstruct,
+	}
+}
+
+// enclosingConstruct walks up from node through its ancestors looking for
+// the nearest function_item or struct_item, returning "<kind>:<name>" - or
+// "" if node isn't nested inside one.
+func enclosingConstruct(node tree_sitter.Node, source []byte) string {
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		switch parent.Kind() {
+		case "function_item", "struct_item":
+			name := parent.ChildByFieldName("name")
+			if name == nil {
+				return parent.Kind()
+			}
+			return parent.Kind() + ":" + string(source[name.StartByte():name.EndByte()])
+		}
+	}
+	return ""
+}
+
 // extractQueryPattern removes comments and extracts just the query pattern
 func extractQueryPattern(queryContent string) string {
 	lines := strings.Split(queryContent, "\n")
@@ -146,4 +364,4 @@ func extractQueryPattern(queryContent string) string {
 	}
 
 	return strings.Join(queryLines, "\n")
-}
\ No newline at end of file
+}
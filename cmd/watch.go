@@ -0,0 +1,387 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/pkg/concepts"
+	"github.com/jeffrydegrande/solidair/pkg/config"
+	"github.com/jeffrydegrande/solidair/types"
+	"github.com/jeffrydegrande/solidair/variables"
+	"github.com/spf13/cobra"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [dir]",
+	Short: "Watch a directory and re-analyze changed files as they're saved",
+	Long: `Watch observes dir for changes to files with a registered language backend
+(see languages.Backend). On each save it re-parses just that file (reusing
+the previous Tree-sitter tree so the reparse is incremental), re-runs
+query-based analysis against it, and prints only the findings that are new
+or have disappeared since the file's last run. Variable/concept matching is
+Cairo-specific and only runs for .cairo files; variable embeddings are
+cached per file content hash, so saving a file back to a version it's
+already been at reuses embeddings instead of re-requesting them.`,
+	Args: cobra.ExactArgs(1),
+	Run:  watchMain,
+}
+
+func init() {
+	watchCmd.Flags().StringVar(&matchProviderFlag, "provider", "openai", "embedding provider: openai, cohere, voyage, ollama, or llamacpp (ignored with --offline)")
+	watchCmd.Flags().StringVar(&matchModelFlag, "model", "", "model name passed to the provider (defaults to the provider's own default)")
+	watchCmd.Flags().StringVar(&matchHostFlag, "host", "", "server host for ollama/llamacpp providers (defaults to the provider's own default)")
+	rootCmd.AddCommand(watchCmd)
+}
+
+// watchFileState is the incremental-parse and diffing state watchMain keeps
+// for one file between saves.
+type watchFileState struct {
+	source    []byte
+	tree      *tree_sitter.Tree
+	backend   languages.Backend
+	queryKeys map[string]bool // identity of each QueryResult from the last run
+	matchKeys map[string]bool // identity of each ConceptMatch from the last run
+}
+
+func watchMain(cmd *cobra.Command, args []string) {
+	dir := args[0]
+	queryDir, _ := cmd.Flags().GetString("query-dir")
+
+	cfg, err := loadConfig(cmd)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	cache := newQueryCache(queryDir, cfg)
+
+	conceptsDir, _ := cmd.Flags().GetString("concepts-dir")
+	securityConcepts, err := concepts.LoadAllSecurityConcepts(conceptsDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading security concepts: %v\n", err)
+		os.Exit(1)
+	}
+
+	matcher, err := buildMatcher(cmd, securityConcepts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error creating watcher: %v\n", err)
+		os.Exit(1)
+	}
+	defer watcher.Close()
+
+	if err := watchDirs(watcher, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error watching %s: %v\n", dir, err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Watching %s for changes (Ctrl-C to stop)...\n\n", dir)
+
+	files := make(map[string]*watchFileState)
+	// embeddings is keyed by "<file content hash>:<variable Prompt()>" so that
+	// editing a file invalidates just its own stale entries without losing
+	// embeddings for versions of the file seen earlier in the session.
+	embeddings := make(map[string]types.Embedding)
+
+	ctx := context.Background()
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				if info, err := os.Stat(event.Name); err == nil && info.IsDir() {
+					_ = watchDirs(watcher, event.Name)
+					continue
+				}
+			}
+			if _, ok := languages.ForPath(event.Name); !ok {
+				continue
+			}
+			if !event.Has(fsnotify.Write) && !event.Has(fsnotify.Create) {
+				continue
+			}
+			rescanFile(ctx, event.Name, cache, cfg, matcher, files, embeddings)
+		case watchErr, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Watcher error: %v\n", watchErr)
+		}
+	}
+}
+
+// watchDirs adds root and every subdirectory under it to watcher; fsnotify
+// doesn't watch recursively on its own.
+func watchDirs(watcher *fsnotify.Watcher, root string) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
+
+// rescanFile re-parses path incrementally against its previous tree (if
+// any), re-runs query analysis and (for .cairo files) concept matching, and
+// prints only the findings that changed since the last run for this file.
+func rescanFile(ctx context.Context, path string, cache *queryCache, cfg *config.Config, matcher *embedding.EmbeddingMatcher, files map[string]*watchFileState, embeddings map[string]types.Embedding) {
+	backend, ok := languages.ForPath(path)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "No language backend registered for %s\n", path)
+		return
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+		return
+	}
+
+	prev := files[path]
+	tree, err := reparse(source, prev, backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error parsing %s: %v\n", path, err)
+		return
+	}
+
+	queries, err := cache.queriesFor(backend)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading %s queries: %v\n", backend.Name(), err)
+		return
+	}
+
+	results, err := RunQueries(source, tree, backend, queries, cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error running queries on %s: %v\n", path, err)
+		return
+	}
+
+	// Variable/concept matching is written against Cairo's own grammar (see
+	// variables.ExtractVariables), so it only runs when backend resolves to
+	// the same one languages registers for ".cairo" - other backends still
+	// get query-based analysis above.
+	vars := &variables.ExtractedVariables{}
+	if cairoBackend, ok := languages.ForExtension(".cairo"); ok && backend.Name() == cairoBackend.Name() {
+		vars, err = variables.ExtractVariables(source, tree, backend)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error extracting variables from %s: %v\n", path, err)
+			return
+		}
+	}
+
+	hash := contentHash(source)
+	warmMatcherCache(matcher, vars.Variables, embeddings, hash)
+	matchesByConcept, err := matcher.MatchVariables(ctx, vars.Variables)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error matching variables in %s: %v\n", path, err)
+		return
+	}
+	storeMatcherCache(matcher, vars.Variables, embeddings, hash)
+
+	queryKeys := make(map[string]bool, len(results))
+	for _, r := range results {
+		queryKeys[queryResultKey(r)] = true
+	}
+
+	var matches []types.ConceptMatch
+	for _, ms := range matchesByConcept {
+		matches = append(matches, ms...)
+	}
+	matchKeys := make(map[string]bool, len(matches))
+	for _, m := range matches {
+		matchKeys[conceptMatchKey(m)] = true
+	}
+
+	fmt.Printf("--- %s ---\n", path)
+	printQueryDiff(results, queryKeys, prev)
+	printMatchDiff(matches, matchKeys, prev)
+	fmt.Println()
+
+	files[path] = &watchFileState{source: source, tree: tree, backend: backend, queryKeys: queryKeys, matchKeys: matchKeys}
+}
+
+// warmMatcherCache seeds matcher's variable cache with embeddings already
+// computed for this exact file content, so MatchVariables doesn't re-embed
+// variables whose prompt and containing file haven't changed since last
+// time. Keyed by v.Prompt() rather than v.Name, matching what
+// EmbeddingMatcher itself now embeds and caches under.
+func warmMatcherCache(matcher *embedding.EmbeddingMatcher, vars []types.VariableInfo, embeddings map[string]types.Embedding, hash string) {
+	for _, v := range vars {
+		if e, ok := embeddings[hash+":"+v.Prompt()]; ok {
+			matcher.Cache.Variables[v.Prompt()] = e
+		}
+	}
+}
+
+// storeMatcherCache copies embeddings MatchVariables just computed (or
+// reused) back into the file-hash-keyed cache for future saves.
+func storeMatcherCache(matcher *embedding.EmbeddingMatcher, vars []types.VariableInfo, embeddings map[string]types.Embedding, hash string) {
+	for _, v := range vars {
+		if e, ok := matcher.Cache.Variables[v.Prompt()]; ok {
+			embeddings[hash+":"+v.Prompt()] = e
+		}
+	}
+}
+
+// reparse parses source, reusing prev's tree via tree-sitter's incremental
+// edit API when available so only the changed region needs re-parsing.
+func reparse(source []byte, prev *watchFileState, backend languages.Backend) (*tree_sitter.Tree, error) {
+	if prev == nil {
+		return reparseTree(source, nil, nil, backend)
+	}
+	return reparseTree(source, prev.source, prev.tree, backend)
+}
+
+// reparseTree parses source with backend's grammar, reusing prevTree via
+// tree-sitter's incremental edit API when prevSource/prevTree are given
+// (both nil means parse from scratch) so only the changed region needs
+// re-parsing. Shared by watch's reparse and the lsp server's own incremental
+// reparsing.
+func reparseTree(source, prevSource []byte, prevTree *tree_sitter.Tree, backend languages.Backend) (*tree_sitter.Tree, error) {
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(backend.TSLanguage()); err != nil {
+		return nil, fmt.Errorf("error setting language: %w", err)
+	}
+
+	if prevTree == nil {
+		return parser.Parse(source, nil), nil
+	}
+
+	edit := computeWatchEdit(prevSource, source)
+	prevTree.Edit(&edit)
+	tree := parser.Parse(source, prevTree)
+	prevTree.Close()
+	return tree, nil
+}
+
+// computeWatchEdit finds the common prefix/suffix between old and new source
+// and builds the InputEdit tree-sitter needs to reparse incrementally.
+func computeWatchEdit(old, new []byte) tree_sitter.InputEdit {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+
+	oldSuffix, newSuffix := len(old), len(new)
+	for oldSuffix > prefix && newSuffix > prefix && old[oldSuffix-1] == new[newSuffix-1] {
+		oldSuffix--
+		newSuffix--
+	}
+
+	return tree_sitter.InputEdit{
+		StartByte:      uint(prefix),
+		OldEndByte:     uint(oldSuffix),
+		NewEndByte:     uint(newSuffix),
+		StartPosition:  watchPointAt(old, prefix),
+		OldEndPosition: watchPointAt(old, oldSuffix),
+		NewEndPosition: watchPointAt(new, newSuffix),
+	}
+}
+
+// watchPointAt converts a byte offset into a row/column Point by scanning
+// for newlines, the same convention tree-sitter itself uses.
+func watchPointAt(source []byte, offset int) tree_sitter.Point {
+	row, col := uint(0), uint(0)
+	for i := 0; i < offset && i < len(source); i++ {
+		if source[i] == '\n' {
+			row++
+			col = 0
+		} else {
+			col++
+		}
+	}
+	return tree_sitter.Point{Row: row, Column: col}
+}
+
+// contentHash hashes a file's contents so cached embeddings can be scoped to
+// the exact version of the file they were computed against.
+func contentHash(source []byte) string {
+	sum := sha256.Sum256(source)
+	return hex.EncodeToString(sum[:])
+}
+
+// queryResultKey identifies a QueryResult for diffing across runs.
+func queryResultKey(r QueryResult) string {
+	return fmt.Sprintf("%s|%d|%s", r.QueryName, r.LineNumber, r.Code)
+}
+
+// conceptMatchKey identifies a ConceptMatch for diffing across runs.
+func conceptMatchKey(m types.ConceptMatch) string {
+	return fmt.Sprintf("%s|%s|%d", m.Concept, m.Variable.Name, m.Variable.LineNumber)
+}
+
+// printQueryDiff prints only the query findings that are new since prev, and
+// notes how many previously-reported findings have been resolved.
+func printQueryDiff(results []QueryResult, keys map[string]bool, prev *watchFileState) {
+	var resolved int
+	if prev != nil {
+		for k := range prev.queryKeys {
+			if !keys[k] {
+				resolved++
+			}
+		}
+	}
+
+	var newCount int
+	for _, r := range results {
+		if prev != nil && prev.queryKeys[queryResultKey(r)] {
+			continue
+		}
+		newCount++
+		fmt.Printf("  [new] %s (line %d): %s\n", r.QueryName, r.LineNumber, r.Code)
+	}
+
+	if newCount == 0 && resolved == 0 {
+		fmt.Printf("  %d vulnerability findings, unchanged\n", len(results))
+	} else if resolved > 0 {
+		fmt.Printf("  %d finding(s) resolved\n", resolved)
+	}
+}
+
+// printMatchDiff prints only the concept matches that are new since prev,
+// and notes how many previously-reported matches have disappeared.
+func printMatchDiff(matches []types.ConceptMatch, keys map[string]bool, prev *watchFileState) {
+	var resolved int
+	if prev != nil {
+		for k := range prev.matchKeys {
+			if !keys[k] {
+				resolved++
+			}
+		}
+	}
+
+	var newCount int
+	for _, m := range matches {
+		if prev != nil && prev.matchKeys[conceptMatchKey(m)] {
+			continue
+		}
+		newCount++
+		fmt.Printf("  [new match] %s -> %s (line %d, score %.4f)\n", m.Variable.Name, m.Concept, m.Variable.LineNumber, m.SimilarityScore)
+	}
+
+	if newCount == 0 && resolved == 0 {
+		fmt.Printf("  %d concept matches, unchanged\n", len(matches))
+	} else if resolved > 0 {
+		fmt.Printf("  %d match(es) resolved\n", resolved)
+	}
+}
@@ -0,0 +1,344 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/jeffrydegrande/solidair/types"
+	tiktoken "github.com/pkoukk/tiktoken-go"
+)
+
+// maxBatchInputs is the largest number of texts OpenAI's embeddings endpoint
+// accepts in a single request (text-embedding-ada-002 and friends).
+const maxBatchInputs = 2048
+
+// maxBatchTokens is the largest total token count OpenAI's embeddings
+// endpoint accepts per request.
+const maxBatchTokens = 8191
+
+// maxRetries is the number of retry attempts BatchEmbedder makes for a
+// single slice after a failed provider call (any error, not only 429/5xx),
+// beyond the initial try.
+const maxRetries = 5
+
+// baseBackoff is the delay before the first retry; each subsequent retry
+// doubles it (capped at maxBackoff) and adds jitter.
+const baseBackoff = 500 * time.Millisecond
+
+// maxBackoff caps the exponential backoff delay so a long run of failures
+// doesn't end up sleeping for minutes between attempts.
+const maxBackoff = 30 * time.Second
+
+// tiktokenEncoding is the tokenizer used to size batches; cl100k_base is
+// what text-embedding-ada-002 and the other OpenAI embedding models use.
+const tiktokenEncoding = "cl100k_base"
+
+// BatchEmbedder wraps an EmbeddingProvider so that bulk embedding a large
+// set of texts (an initial index build over a whole Cairo repo, say) doesn't
+// issue one HTTP call per input and doesn't exceed the provider's per-request
+// batch size or token limit. Inputs are sliced into batches that respect
+// both limits, each batch is retried with exponential backoff + jitter on
+// failure, and results stream back through a channel in input order.
+type BatchEmbedder struct {
+	Provider EmbeddingProvider
+
+	// MaxInputs and MaxTokens bound each request sent to Provider. Both
+	// default to the OpenAI ada-002 limits when left zero.
+	MaxInputs int
+	MaxTokens int
+
+	// RateLimiter throttles how fast batches are issued. Nil disables
+	// throttling (the default providers other than OpenAI don't need it).
+	RateLimiter *TokenBucket
+
+	// MaxRetries and BaseBackoff override the default retry schedule
+	// (maxRetries attempts, starting at baseBackoff and doubling). Tests
+	// shrink these to keep a simulated-failure run fast; production callers
+	// should leave them at zero.
+	MaxRetries  int
+	BaseBackoff time.Duration
+
+	// OnBatch, if set, is called from EmbedStream once per completed batch
+	// (success or failure), after that batch's results have been sent, with
+	// the number of texts attempted so far and the total requested. Nil by
+	// default; a CLI command that wants progress output (e.g.
+	// embeddings rebuild) sets it before calling EmbedStream/Embed.
+	OnBatch func(done, total int)
+
+	enc *tiktoken.Tiktoken
+}
+
+var _ EmbeddingProvider = (*BatchEmbedder)(nil)
+
+// NewBatchEmbedder wraps provider with OpenAI's default batch-size and
+// token limits and no rate limiting.
+func NewBatchEmbedder(provider EmbeddingProvider) *BatchEmbedder {
+	return &BatchEmbedder{Provider: provider, MaxInputs: maxBatchInputs, MaxTokens: maxBatchTokens}
+}
+
+// BatchResult is one text's outcome from EmbedStream, tagged with its
+// position in the original input slice so callers can reassemble order from
+// a channel that may deliver batches out of sequence... it cannot actually
+// happen since batches are issued and drained one at a time, but keeping the
+// index alongside the value makes that guarantee explicit rather than
+// implicit in iteration order.
+type BatchResult struct {
+	Index     int
+	Embedding types.Embedding
+	Err       error
+}
+
+// Embed implements EmbeddingProvider, making BatchEmbedder a drop-in
+// replacement for the provider it wraps: embed texts in as few provider
+// calls as possible, each respecting MaxInputs/MaxTokens, retrying failed
+// batches with exponential backoff, and return a slice aligned with texts
+// (result[i] is texts[i]'s embedding). Callers that want progress as each
+// batch completes, rather than one all-or-nothing result, should use
+// EmbedStream directly instead.
+func (b *BatchEmbedder) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	results := make([]types.Embedding, len(texts))
+	for res := range b.EmbedStream(ctx, texts) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		results[res.Index] = res.Embedding
+	}
+	return results, nil
+}
+
+// Dimension implements EmbeddingProvider by delegating to the wrapped provider.
+func (b *BatchEmbedder) Dimension() int { return b.Provider.Dimension() }
+
+// ID implements EmbeddingProvider by delegating to the wrapped provider.
+func (b *BatchEmbedder) ID() string { return b.Provider.ID() }
+
+// EmbedStream embeds texts in batches sized under MaxInputs/MaxTokens and
+// streams each text's result back through the returned channel as soon as
+// its batch completes, preserving the original input order via
+// BatchResult.Index. The channel is closed once every batch has been
+// attempted or ctx is cancelled. A batch that exhausts its retries sends one
+// BatchResult.Err per text in that batch and processing continues with the
+// next batch.
+func (b *BatchEmbedder) EmbedStream(ctx context.Context, texts []string) <-chan BatchResult {
+	out := make(chan BatchResult)
+
+	go func() {
+		defer close(out)
+
+		var done int
+		for _, batch := range b.slice(texts) {
+			if ctx.Err() != nil {
+				for _, idx := range batch.indices {
+					out <- BatchResult{Index: idx, Err: ctx.Err()}
+				}
+				done += len(batch.indices)
+				b.reportProgress(done, len(texts))
+				continue
+			}
+
+			if b.RateLimiter != nil {
+				if err := b.RateLimiter.Wait(ctx, batch.tokens); err != nil {
+					for _, idx := range batch.indices {
+						out <- BatchResult{Index: idx, Err: err}
+					}
+					done += len(batch.indices)
+					b.reportProgress(done, len(texts))
+					continue
+				}
+			}
+
+			embeddings, err := b.embedWithRetry(ctx, batch.texts)
+			if err != nil {
+				for _, idx := range batch.indices {
+					out <- BatchResult{Index: idx, Err: err}
+				}
+				done += len(batch.indices)
+				b.reportProgress(done, len(texts))
+				continue
+			}
+
+			for i, idx := range batch.indices {
+				out <- BatchResult{Index: idx, Embedding: embeddings[i]}
+			}
+			done += len(batch.indices)
+			b.reportProgress(done, len(texts))
+		}
+	}()
+
+	return out
+}
+
+// reportProgress invokes OnBatch if the caller set one, after a batch has
+// been fully processed (successfully or not).
+func (b *BatchEmbedder) reportProgress(done, total int) {
+	if b.OnBatch != nil {
+		b.OnBatch(done, total)
+	}
+}
+
+// embedWithRetry calls Provider.Embed, retrying up to maxRetries times with
+// exponential backoff and jitter. It doesn't distinguish 429/5xx from other
+// failures by status code since EmbeddingProvider.Embed only returns an
+// error, not the HTTP response; any error is treated as retryable up to the
+// limit, which is safe here since a batch that genuinely can't succeed
+// (bad input) just burns through the retries once.
+func (b *BatchEmbedder) embedWithRetry(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	retries := b.MaxRetries
+	if retries <= 0 {
+		retries = maxRetries
+	}
+	base := b.BaseBackoff
+	if base <= 0 {
+		base = baseBackoff
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			delay := backoffDelay(attempt, base)
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(delay):
+			}
+		}
+
+		embeddings, err := b.Provider.Embed(ctx, texts)
+		if err == nil {
+			return embeddings, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("embedding batch of %d texts failed after %d retries: %w", len(texts), retries, lastErr)
+}
+
+// backoffDelay is the exponential-backoff-plus-jitter delay before retry
+// attempt n (n >= 1): base * 2^(n-1), capped at maxBackoff, then jittered by
+// +/-50% so many failing batches don't retry in lockstep.
+func backoffDelay(attempt int, base time.Duration) time.Duration {
+	delay := time.Duration(float64(base) * math.Pow(2, float64(attempt-1)))
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	jitter := time.Duration((rand.Float64() - 0.5) * float64(delay))
+	return delay + jitter
+}
+
+// textBatch is one provider-sized slice of the original input: the texts
+// themselves, their positions in the caller's slice, and the token count
+// used to size it.
+type textBatch struct {
+	texts   []string
+	indices []int
+	tokens  int
+}
+
+// slice splits texts into batches of at most MaxInputs texts and MaxTokens
+// tokens each, counting tokens with tiktoken so a batch never exceeds the
+// provider's per-request limit even when individual texts are short but
+// numerous, or few but long.
+func (b *BatchEmbedder) slice(texts []string) []textBatch {
+	maxInputs := b.MaxInputs
+	if maxInputs <= 0 {
+		maxInputs = maxBatchInputs
+	}
+	maxTokens := b.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = maxBatchTokens
+	}
+
+	var batches []textBatch
+	var current textBatch
+
+	for i, text := range texts {
+		tokens := b.countTokens(text)
+
+		if len(current.texts) > 0 && (len(current.texts) >= maxInputs || current.tokens+tokens > maxTokens) {
+			batches = append(batches, current)
+			current = textBatch{}
+		}
+
+		current.texts = append(current.texts, text)
+		current.indices = append(current.indices, i)
+		current.tokens += tokens
+	}
+
+	if len(current.texts) > 0 {
+		batches = append(batches, current)
+	}
+
+	return batches
+}
+
+// countTokens returns text's token count under cl100k_base, falling back to
+// a conservative character-based estimate if the encoding fails to load
+// (e.g. no network access to fetch its vocabulary file).
+func (b *BatchEmbedder) countTokens(text string) int {
+	if b.enc == nil {
+		enc, err := tiktoken.GetEncoding(tiktokenEncoding)
+		if err != nil {
+			return estimateTokens(text)
+		}
+		b.enc = enc
+	}
+	return len(b.enc.Encode(text, nil, nil))
+}
+
+// estimateTokens approximates a token count as one token per four
+// characters, OpenAI's rule of thumb for English text, used only when the
+// tiktoken encoding can't be loaded.
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// TokenBucket is a token-bucket rate limiter sized in embedding-API tokens
+// rather than requests, since that's what providers like OpenAI meter.
+type TokenBucket struct {
+	capacity   int
+	tokens     float64
+	refillRate float64 // tokens per second
+	last       time.Time
+}
+
+// NewTokenBucket creates a bucket that holds up to capacity tokens and
+// refills at refillPerSecond tokens/second, starting full.
+func NewTokenBucket(capacity int, refillPerSecond float64) *TokenBucket {
+	return &TokenBucket{
+		capacity:   capacity,
+		tokens:     float64(capacity),
+		refillRate: refillPerSecond,
+		last:       time.Now(),
+	}
+}
+
+// Wait blocks until n tokens are available (refilling as time passes) or ctx
+// is cancelled.
+func (t *TokenBucket) Wait(ctx context.Context, n int) error {
+	for {
+		t.refill()
+		if t.tokens >= float64(n) {
+			t.tokens -= float64(n)
+			return nil
+		}
+
+		deficit := float64(n) - t.tokens
+		wait := time.Duration(deficit / t.refillRate * float64(time.Second))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// refill adds tokens earned since the last call, capped at capacity.
+func (t *TokenBucket) refill() {
+	now := time.Now()
+	elapsed := now.Sub(t.last).Seconds()
+	t.last = now
+	t.tokens = math.Min(float64(t.capacity), t.tokens+elapsed*t.refillRate)
+}
@@ -0,0 +1,218 @@
+package embedding_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// fakeProvider is a stub EmbeddingProvider that records the batches it was
+// called with and can be made to fail a configurable number of times before
+// succeeding, so tests can exercise BatchEmbedder's slicing and retry logic
+// without a real network call.
+type fakeProvider struct {
+	mu         sync.Mutex
+	calls      [][]string
+	failTimes  int
+	maxPerCall int
+}
+
+func (f *fakeProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, append([]string(nil), texts...))
+	fail := f.failTimes > 0
+	if fail {
+		f.failTimes--
+	}
+	maxPerCall := f.maxPerCall
+	f.mu.Unlock()
+
+	if fail {
+		return nil, fmt.Errorf("simulated 429")
+	}
+	if maxPerCall > 0 && len(texts) > maxPerCall {
+		return nil, fmt.Errorf("batch of %d exceeds provider max %d", len(texts), maxPerCall)
+	}
+
+	result := make([]types.Embedding, len(texts))
+	for i, text := range texts {
+		result[i] = types.Embedding{Vector: []float32{float32(len(text))}, ModelID: "fake"}
+	}
+	return result, nil
+}
+
+func (f *fakeProvider) Dimension() int { return 1 }
+func (f *fakeProvider) ID() string     { return "fake:test" }
+
+func TestBatchEmbedderPreservesOrder(t *testing.T) {
+	fp := &fakeProvider{}
+	b := &embedding.BatchEmbedder{Provider: fp, MaxInputs: 2}
+
+	texts := []string{"alpha", "bb", "ccc", "dddd", "e"}
+	got, err := b.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(got) != len(texts) {
+		t.Fatalf("expected %d embeddings, got %d", len(texts), len(got))
+	}
+	for i, text := range texts {
+		if got[i].Vector[0] != float32(len(text)) {
+			t.Errorf("result %d: expected embedding for %q, got vector %v", i, text, got[i].Vector)
+		}
+	}
+	if len(fp.calls) < 2 {
+		t.Errorf("expected texts to be split across multiple calls with MaxInputs=2, got %d call(s)", len(fp.calls))
+	}
+	for _, call := range fp.calls {
+		if len(call) > 2 {
+			t.Errorf("expected calls of at most 2 texts, got %d: %v", len(call), call)
+		}
+	}
+}
+
+func TestBatchEmbedderRetriesOnFailure(t *testing.T) {
+	fp := &fakeProvider{failTimes: 2}
+	b := &embedding.BatchEmbedder{Provider: fp, BaseBackoff: time.Millisecond}
+
+	got, err := b.Embed(context.Background(), []string{"hello"})
+	if err != nil {
+		t.Fatalf("Embed() error = %v, want success after retries", err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 embedding, got %d", len(got))
+	}
+	if len(fp.calls) != 3 {
+		t.Errorf("expected 3 calls (2 failures + 1 success), got %d", len(fp.calls))
+	}
+}
+
+func TestBatchEmbedderExhaustsRetries(t *testing.T) {
+	fp := &fakeProvider{failTimes: 1000}
+	b := &embedding.BatchEmbedder{Provider: fp, MaxRetries: 2, BaseBackoff: time.Millisecond}
+
+	_, err := b.Embed(context.Background(), []string{"hello"})
+	if err == nil {
+		t.Fatal("expected an error after exhausting retries, got nil")
+	}
+}
+
+func TestBatchEmbedderRespectsMaxInputs(t *testing.T) {
+	fp := &fakeProvider{maxPerCall: 3}
+	b := &embedding.BatchEmbedder{Provider: fp, MaxInputs: 3}
+
+	texts := make([]string, 10)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("var%d", i)
+	}
+
+	if _, err := b.Embed(context.Background(), texts); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+}
+
+func TestBatchEmbedderRespectsMaxTokens(t *testing.T) {
+	fp := &fakeProvider{}
+	long := strings.Repeat("word ", 200) // well over a handful of tokens
+	b := &embedding.BatchEmbedder{Provider: fp, MaxInputs: 100, MaxTokens: 50}
+
+	if _, err := b.Embed(context.Background(), []string{long, long, long}); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(fp.calls) < 2 {
+		t.Errorf("expected long texts to be split across multiple calls under MaxTokens=50, got %d call(s)", len(fp.calls))
+	}
+}
+
+func TestBatchEmbedderStreamDeliversAllResults(t *testing.T) {
+	fp := &fakeProvider{}
+	b := &embedding.BatchEmbedder{Provider: fp, MaxInputs: 2}
+
+	texts := []string{"a", "bb", "ccc", "dddd"}
+	seen := make(map[int]bool)
+	for res := range b.EmbedStream(context.Background(), texts) {
+		if res.Err != nil {
+			t.Fatalf("unexpected error for index %d: %v", res.Index, res.Err)
+		}
+		seen[res.Index] = true
+	}
+	if len(seen) != len(texts) {
+		t.Errorf("expected results for all %d texts, got %d", len(texts), len(seen))
+	}
+}
+
+func TestBatchEmbedderReportsProgress(t *testing.T) {
+	fp := &fakeProvider{}
+	b := &embedding.BatchEmbedder{Provider: fp, MaxInputs: 2}
+
+	var mu sync.Mutex
+	var seen []int
+	b.OnBatch = func(done, total int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if total != 5 {
+			t.Errorf("OnBatch total = %d, want 5", total)
+		}
+		seen = append(seen, done)
+	}
+
+	texts := []string{"alpha", "bb", "ccc", "dddd", "e"}
+	if _, err := b.Embed(context.Background(), texts); err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	if len(seen) == 0 {
+		t.Fatal("expected OnBatch to be called at least once")
+	}
+	if last := seen[len(seen)-1]; last != len(texts) {
+		t.Errorf("final OnBatch done = %d, want %d", last, len(texts))
+	}
+}
+
+func TestBatchEmbedderDelegatesDimensionAndID(t *testing.T) {
+	fp := &fakeProvider{}
+	b := embedding.NewBatchEmbedder(fp)
+
+	if b.Dimension() != fp.Dimension() {
+		t.Errorf("Dimension() = %d, want %d", b.Dimension(), fp.Dimension())
+	}
+	if b.ID() != fp.ID() {
+		t.Errorf("ID() = %q, want %q", b.ID(), fp.ID())
+	}
+}
+
+func TestTokenBucketThrottles(t *testing.T) {
+	bucket := embedding.NewTokenBucket(10, 1000) // generous refill so the test stays fast
+	if err := bucket.Wait(context.Background(), 5); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if err := bucket.Wait(context.Background(), 5); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+
+	// The bucket is now drained, so a third Wait has to block until ~5
+	// tokens have refilled (about 5ms at 1000/s).
+	start := time.Now()
+	if err := bucket.Wait(context.Background(), 5); err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Millisecond {
+		t.Errorf("third Wait() returned after %v, want it to block until the bucket refilled", elapsed)
+	}
+}
+
+func TestTokenBucketRespectsContextCancellation(t *testing.T) {
+	bucket := embedding.NewTokenBucket(1, 0.001) // near-zero refill so the wait would hang
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := bucket.Wait(ctx, 1000); err == nil {
+		t.Error("expected context cancellation error, got nil")
+	}
+}
@@ -0,0 +1,68 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// CodeChunk is a span of source code together with the surrounding context
+// needed to make its embedding meaningful: the enclosing function/struct, any
+// doc comments, and its location. Unlike a bare identifier, a chunk carries
+// enough of the code around it that a query like "reentrancy guard" can match
+// on what the code does rather than just what a variable is named.
+type CodeChunk struct {
+	FilePath   string   // Source file the chunk came from
+	StartLine  uint32   // First line of the chunk (1-based)
+	EndLine    uint32   // Last line of the chunk (1-based)
+	ParentName string   // Enclosing function or struct, if any
+	Comments   []string // Doc comments immediately preceding the chunk
+	Code       string   // The chunk's source text
+}
+
+// Prompt builds the text that gets embedded for this chunk: comments first
+// (they carry intent), then the enclosing scope, then the code itself.
+func (c CodeChunk) Prompt() string {
+	var b strings.Builder
+	for _, comment := range c.Comments {
+		b.WriteString(comment)
+		b.WriteString("\n")
+	}
+	if c.ParentName != "" {
+		fmt.Fprintf(&b, "in %s:\n", c.ParentName)
+	}
+	b.WriteString(c.Code)
+	return b.String()
+}
+
+// MatchChunks embeds each chunk's prompt and matches it against the
+// matcher's security concepts, the same way MatchVariable does for
+// identifiers. It lets templates and queries be gated on code that is
+// semantically about a concept, not just on a variable's name.
+func (m *EmbeddingMatcher) MatchChunks(ctx context.Context, chunks []CodeChunk) ([]types.ChunkMatch, error) {
+	var matches []types.ChunkMatch
+
+	for _, chunk := range chunks {
+		chunkEmbedding, err := m.embedCached(ctx, chunk.Prompt())
+		if err != nil {
+			return nil, fmt.Errorf("error embedding chunk %s:%d: %w", chunk.FilePath, chunk.StartLine, err)
+		}
+
+		for _, concept := range m.Concepts {
+			similarity := CosineSimilarity(chunkEmbedding, concept.Embedding)
+			if similarity >= m.SimilarityThreshold {
+				matches = append(matches, types.ChunkMatch{
+					FilePath:        chunk.FilePath,
+					StartLine:       chunk.StartLine,
+					EndLine:         chunk.EndLine,
+					Concept:         concept.Name,
+					SimilarityScore: similarity,
+				})
+			}
+		}
+	}
+
+	return matches, nil
+}
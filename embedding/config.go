@@ -0,0 +1,67 @@
+package embedding
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// EmbedderConfig names one entry in embedders.toml: a provider instance
+// callers can refer to by Name (e.g. as SecurityConcept.PreferredEmbedder)
+// without repeating its kind/model/endpoint everywhere.
+type EmbedderConfig struct {
+	Name       string `toml:"name"`
+	Kind       string `toml:"kind"` // openai, huggingface, cohere, voyage, ollama, llamacpp, local
+	Model      string `toml:"model,omitempty"`
+	Endpoint   string `toml:"endpoint,omitempty"`
+	APIKeyEnv  string `toml:"api_key_env,omitempty"`
+	Dimensions int    `toml:"dimensions,omitempty"`
+}
+
+// LoadEmbedderConfigs reads embedders.toml, the multi-embedder counterpart
+// to the single --provider/--model/--host flags NewProvider takes directly.
+func LoadEmbedderConfigs(path string) ([]EmbedderConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedders config: %w", err)
+	}
+
+	var config struct {
+		Embedders []EmbedderConfig `toml:"embedders"`
+	}
+	if err := toml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing embedders config: %w", err)
+	}
+
+	return config.Embedders, nil
+}
+
+// NewProviderFromConfig builds the EmbeddingProvider named by cfg, resolving
+// its API key from APIKeyEnv and applying Dimensions to providers whose
+// vector length can't be inferred from Kind/Model alone.
+func NewProviderFromConfig(cfg EmbedderConfig) (EmbeddingProvider, error) {
+	apiKey := ""
+	if cfg.APIKeyEnv != "" {
+		apiKey = os.Getenv(cfg.APIKeyEnv)
+		if apiKey == "" {
+			return nil, fmt.Errorf("embedder %q: %s is not set", cfg.Name, cfg.APIKeyEnv)
+		}
+	}
+
+	provider, err := NewProvider(cfg.Kind, cfg.Model, cfg.Endpoint, apiKey)
+	if err != nil {
+		return nil, fmt.Errorf("embedder %q: %w", cfg.Name, err)
+	}
+
+	if cfg.Dimensions > 0 {
+		switch p := provider.(type) {
+		case *LlamaCppProvider:
+			p.Dims = cfg.Dimensions
+		case *HuggingFaceProvider:
+			p.Dims = cfg.Dimensions
+		}
+	}
+
+	return provider, nil
+}
@@ -6,6 +6,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/jeffrydegrande/solidair/types"
 	"github.com/pelletier/go-toml/v2"
@@ -20,39 +21,66 @@ type EmbeddingCache struct {
 // OpenAIClient represents a client for the OpenAI API
 type OpenAIClient struct {
 	Client *openai.Client
+	Model  openai.EmbeddingModel
 }
 
-// NewOpenAIClient creates a new OpenAI client with the provided API key
+// NewOpenAIClient creates a new OpenAI client with the provided API key,
+// pinned to the default text-embedding-ada-002 model.
 func NewOpenAIClient(apiKey string) *OpenAIClient {
+	return NewOpenAIClientWithModel(apiKey, openai.AdaEmbeddingV2)
+}
+
+// NewOpenAIClientWithModel creates a client pinned to a specific embedding
+// model, e.g. openai.SmallEmbedding3 or openai.LargeEmbedding3, for callers
+// that want something other than the ada-002 default.
+func NewOpenAIClientWithModel(apiKey string, model openai.EmbeddingModel) *OpenAIClient {
+	if model == "" {
+		model = openai.AdaEmbeddingV2
+	}
 	return &OpenAIClient{
 		Client: openai.NewClient(apiKey),
+		Model:  model,
 	}
 }
 
 // GetEmbedding calculates an embedding for the given text using OpenAI's API
 func (c *OpenAIClient) GetEmbedding(ctx context.Context, text string) (types.Embedding, error) {
+	embeddings, err := c.GetEmbeddings(ctx, []string{text})
+	if err != nil {
+		return types.Embedding{}, err
+	}
+	return embeddings[0], nil
+}
+
+// GetEmbeddings calculates embeddings for a batch of texts in a single API
+// call, rather than one request per text.
+func (c *OpenAIClient) GetEmbeddings(ctx context.Context, texts []string) ([]types.Embedding, error) {
 	resp, err := c.Client.CreateEmbeddings(
 		ctx,
 		openai.EmbeddingRequest{
-			Input: []string{text},
-			Model: openai.AdaEmbeddingV2,
+			Input: texts,
+			Model: c.Model,
 		},
 	)
 	if err != nil {
-		return types.Embedding{}, fmt.Errorf("error getting embedding: %w", err)
+		return nil, fmt.Errorf("error getting embedding: %w", err)
 	}
 
-	if len(resp.Data) == 0 {
-		return types.Embedding{}, fmt.Errorf("no embedding data returned")
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
 	}
 
-	// Convert from []float64 to []float32 to save memory
-	vector := make([]float32, len(resp.Data[0].Embedding))
-	for i, v := range resp.Data[0].Embedding {
-		vector[i] = float32(v)
+	result := make([]types.Embedding, len(resp.Data))
+	for i, d := range resp.Data {
+		// Convert from []float64 to []float32 to save memory
+		vector := make([]float32, len(d.Embedding))
+		for j, v := range d.Embedding {
+			vector[j] = float32(v)
+		}
+		result[i] = types.Embedding{Vector: vector, ModelID: string(c.Model)}
 	}
 
-	return types.Embedding{Vector: vector}, nil
+	return result, nil
 }
 
 // CosineSimilarity calculates the cosine similarity between two embeddings
@@ -87,6 +115,21 @@ func CosineSimilarity(a, b types.Embedding) float32 {
 	return dotProduct / (normA * normB)
 }
 
+// CosineSimilarityChecked is CosineSimilarity plus a guard against comparing
+// vectors from different embedding backends: two models can produce vectors
+// of the same length whose dimensions mean entirely different things, so a
+// dimension match alone isn't enough to trust the result. Callers that mix
+// embeddings from more than one provider (e.g. after swapping --provider, or
+// reading a Store populated by an older model) should use this instead of
+// CosineSimilarity, which stays silent-zero-on-mismatch for ranking code
+// that already guarantees same-model inputs.
+func CosineSimilarityChecked(a, b types.Embedding) (float32, error) {
+	if a.ModelID != "" && b.ModelID != "" && a.ModelID != b.ModelID {
+		return 0, fmt.Errorf("cannot compare embeddings from different models: %q vs %q", a.ModelID, b.ModelID)
+	}
+	return CosineSimilarity(a, b), nil
+}
+
 // SaveEmbeddingsFile saves all embeddings to a single file
 func SaveEmbeddingsFile(embeddings []types.EmbeddingEntry, outputDir string) error {
 	// Ensure directory exists
@@ -154,13 +197,44 @@ func ContainsIgnoreCase(s, substr string) bool {
 
 // Helper implementation of case-insensitive contains
 func contains(s, substr string) bool {
-	// A simple implementation would be strings.Contains(strings.ToLower(s), strings.ToLower(substr))
-	// But we could implement a more sophisticated version if needed
-	return true // Placeholder
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
 }
 
-// CalculateNGramSimilarity computes n-gram similarity between two strings
+// CalculateNGramSimilarity computes trigram similarity between two strings
+// as a Dice coefficient (2 * shared trigrams / total trigrams), a cheap
+// fuzzy-match score for offline mode when neither string contains the
+// other.
 func CalculateNGramSimilarity(s1, s2 string) float32 {
-	return 0.5 // Placeholder
+	grams1 := ngramSet(s1, 3)
+	grams2 := ngramSet(s2, 3)
+	if len(grams1) == 0 || len(grams2) == 0 {
+		return 0
+	}
+
+	var shared int
+	for g := range grams1 {
+		if grams2[g] {
+			shared++
+		}
+	}
+
+	return float32(2*shared) / float32(len(grams1)+len(grams2))
 }
 
+// ngramSet lowercases s and returns the set of its character n-grams.
+func ngramSet(s string, n int) map[string]bool {
+	s = strings.ToLower(s)
+	runes := []rune(s)
+	if len(runes) < n {
+		if len(runes) == 0 {
+			return nil
+		}
+		return map[string]bool{s: true}
+	}
+
+	grams := make(map[string]bool, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		grams[string(runes[i:i+n])] = true
+	}
+	return grams
+}
@@ -0,0 +1,118 @@
+package embedding_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+)
+
+// writeTestGGUF builds a minimal GGUF file at dir/name.gguf with a
+// tokenizer.ggml.tokens vocabulary of vocab and a token_embd.weight tensor
+// whose rows are rows (rows[i] is vocab[i]'s embedding), and returns its path.
+func writeTestGGUF(t *testing.T, dir string, vocab []string, rows [][]float32) string {
+	t.Helper()
+
+	var buf bytes.Buffer
+	buf.WriteString("GGUF")
+	binary.Write(&buf, binary.LittleEndian, uint32(3)) // version
+	binary.Write(&buf, binary.LittleEndian, uint64(1)) // tensor_count
+	binary.Write(&buf, binary.LittleEndian, uint64(1)) // metadata_kv_count
+
+	writeGGUFString(&buf, "tokenizer.ggml.tokens")
+	binary.Write(&buf, binary.LittleEndian, uint32(9)) // ggufArray
+	binary.Write(&buf, binary.LittleEndian, uint32(8)) // element type: ggufString
+	binary.Write(&buf, binary.LittleEndian, uint64(len(vocab)))
+	for _, tok := range vocab {
+		writeGGUFString(&buf, tok)
+	}
+
+	nEmbd := len(rows[0])
+	nVocab := len(rows)
+	writeGGUFString(&buf, "token_embd.weight")
+	binary.Write(&buf, binary.LittleEndian, uint32(2)) // n_dims
+	binary.Write(&buf, binary.LittleEndian, uint64(nEmbd))
+	binary.Write(&buf, binary.LittleEndian, uint64(nVocab))
+	binary.Write(&buf, binary.LittleEndian, uint32(0)) // ggml type: F32
+	binary.Write(&buf, binary.LittleEndian, uint64(0)) // offset, relative to aligned data start
+
+	for buf.Len()%32 != 0 {
+		buf.WriteByte(0)
+	}
+	for _, row := range rows {
+		binary.Write(&buf, binary.LittleEndian, row)
+	}
+
+	path := filepath.Join(dir, "test.gguf")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("error writing test GGUF file: %v", err)
+	}
+	return path
+}
+
+func writeGGUFString(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.LittleEndian, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func TestLoadGGUFEmbedderRejectsBadMagic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bad.gguf")
+	if err := os.WriteFile(path, []byte("not a gguf file"), 0o644); err != nil {
+		t.Fatalf("error writing file: %v", err)
+	}
+
+	if _, err := embedding.LoadGGUFEmbedder(path); err == nil {
+		t.Error("expected an error loading a file with the wrong magic, got nil")
+	}
+}
+
+func TestLoadGGUFEmbedderLoadsVocabAndEmbedsKnownToken(t *testing.T) {
+	path := writeTestGGUF(t, t.TempDir(),
+		[]string{"hello", "world"},
+		[][]float32{{1, 0}, {0, 1}},
+	)
+
+	g, err := embedding.LoadGGUFEmbedder(path)
+	if err != nil {
+		t.Fatalf("LoadGGUFEmbedder() error = %v", err)
+	}
+	if g.Dimension() != 2 {
+		t.Fatalf("Dimension() = %d, want 2", g.Dimension())
+	}
+
+	hello := g.Embed("hello")
+	world := g.Embed("world")
+
+	simSame := embedding.CosineSimilarity(hello, hello)
+	simDiff := embedding.CosineSimilarity(hello, world)
+	if simSame <= simDiff {
+		t.Errorf("expected hello to be most similar to itself: sim(hello,hello)=%f, sim(hello,world)=%f", simSame, simDiff)
+	}
+}
+
+func TestGGUFEmbedderFallsBackToHashingForUnknownTokens(t *testing.T) {
+	path := writeTestGGUF(t, t.TempDir(),
+		[]string{"hello"},
+		[][]float32{{1, 0}},
+	)
+
+	g, err := embedding.LoadGGUFEmbedder(path)
+	if err != nil {
+		t.Fatalf("LoadGGUFEmbedder() error = %v", err)
+	}
+
+	a := g.Embed("zzznotinvocab")
+	b := g.Embed("zzznotinvocab")
+	if len(a.Vector) != 2 {
+		t.Fatalf("expected fallback vector of length 2, got %d", len(a.Vector))
+	}
+	for i := range a.Vector {
+		if a.Vector[i] != b.Vector[i] {
+			t.Errorf("expected deterministic fallback output, vector[%d] differed: %f != %f", i, a.Vector[i], b.Vector[i])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package embedding_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+func TestMatchVariableLexicalOnly(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Synonyms: []string{"enabled", "live"}},
+		{Name: "locked", Synonyms: []string{"mutex", "guard"}},
+	}
+
+	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true, nil)
+	matcher.Mode = embedding.LexicalOnly
+	matcher.SimilarityThreshold = 0.8
+
+	matches, err := matcher.MatchVariable(context.Background(), types.VariableInfo{Name: "is_active"})
+	if err != nil {
+		t.Fatalf("MatchVariable() error = %v", err)
+	}
+
+	if len(matches) == 0 || matches[0].Concept != "active" {
+		t.Errorf("expected top lexical match to be 'active', got %+v", matches)
+	}
+}
+
+func TestMatchVariableHybridFusesRankings(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Synonyms: []string{"enabled"}, Embedding: types.Embedding{Vector: []float32{1, 0}}},
+		{Name: "locked", Synonyms: []string{"mutex"}, Embedding: types.Embedding{Vector: []float32{0, 1}}},
+	}
+
+	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true, nil)
+	matcher.Mode = embedding.Hybrid
+	matcher.SimilarityThreshold = 0
+	variable := types.VariableInfo{Name: "is_active"}
+	matcher.Cache.Variables[variable.Prompt()] = types.Embedding{Vector: []float32{1, 0}}
+
+	matches, err := matcher.MatchVariable(context.Background(), variable)
+	if err != nil {
+		t.Fatalf("MatchVariable() error = %v", err)
+	}
+
+	if len(matches) != 2 {
+		t.Fatalf("expected a fused score for every concept, got %d", len(matches))
+	}
+	if matches[0].Concept != "active" {
+		t.Errorf("expected 'active' to rank first under both scorers, got %q", matches[0].Concept)
+	}
+	if matches[0].SimilarityScore <= matches[1].SimilarityScore {
+		t.Errorf("expected fused score to strictly rank 'active' above 'locked'")
+	}
+}
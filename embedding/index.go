@@ -0,0 +1,262 @@
+package embedding
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// ExactScanThreshold is the concept-set size above which MatchVariable
+// switches from a brute-force cosine sweep to ConceptIndex. Below it, the
+// exact scan is cheap enough that building an index isn't worth it.
+const ExactScanThreshold = 256
+
+// defaultM is the bounded fanout per node, per layer (HNSW's "M").
+const defaultM = 16
+
+// defaultEfConstruction is the candidate beam width used while building the
+// graph; larger values trade build time for recall.
+const defaultEfConstruction = 64
+
+// defaultEfSearch is the candidate beam width used by MatchVariable queries.
+const defaultEfSearch = 64
+
+// hnswNode is a single indexed concept, together with its neighbor lists per layer.
+type hnswNode struct {
+	conceptIdx int
+	neighbors  [][]int // neighbors[layer] = list of node indices
+}
+
+// ConceptIndex is an approximate nearest-neighbor index over security
+// concept embeddings, implemented as a hierarchical navigable small world
+// (HNSW) graph: each node lives on a randomly assigned set of layers with a
+// bounded fanout M, search starts at the top layer's entry point and greedily
+// descends, keeping a bounded candidate set at each step.
+type ConceptIndex struct {
+	concepts []types.SecurityConcept
+	nodes    []hnswNode
+	entry    int
+	maxLevel int
+	m        int
+	efConstr int
+	rng      *rand.Rand
+}
+
+// NewConceptIndex creates an empty index. seed makes level assignment
+// deterministic, which keeps test/snapshot output stable across runs.
+func NewConceptIndex(seed int64) *ConceptIndex {
+	return &ConceptIndex{
+		entry:    -1,
+		m:        defaultM,
+		efConstr: defaultEfConstruction,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// Build constructs the graph from scratch over the given concepts.
+func (idx *ConceptIndex) Build(concepts []types.SecurityConcept) {
+	idx.concepts = concepts
+	idx.nodes = make([]hnswNode, len(concepts))
+	idx.entry = -1
+	idx.maxLevel = 0
+
+	for i := range concepts {
+		idx.insert(i)
+	}
+}
+
+// randomLevel draws a node's top layer using the standard HNSW exponential
+// decay distribution with normalization factor 1/ln(M).
+func (idx *ConceptIndex) randomLevel() int {
+	normalizer := 1.0 / math.Log(float64(idx.m))
+	level := int(-math.Log(1-idx.rng.Float64()) * normalizer)
+	if level > 32 {
+		level = 32
+	}
+	return level
+}
+
+func (idx *ConceptIndex) insert(nodeIdx int) {
+	level := idx.randomLevel()
+	idx.nodes[nodeIdx] = hnswNode{
+		conceptIdx: nodeIdx,
+		neighbors:  make([][]int, level+1),
+	}
+
+	if idx.entry == -1 {
+		idx.entry = nodeIdx
+		idx.maxLevel = level
+		return
+	}
+
+	vec := idx.concepts[nodeIdx].Embedding
+	current := idx.entry
+
+	// Descend from the top of the existing graph down to level+1,
+	// greedily moving to the closest neighbor at each layer.
+	for l := idx.maxLevel; l > level; l-- {
+		current = idx.greedyDescend(current, vec, l)
+	}
+
+	// From level down to 0, find efConstruction candidates and connect.
+	for l := min(level, idx.maxLevel); l >= 0; l-- {
+		candidates := idx.searchLayer(vec, current, idx.efConstr, l)
+		neighbors := selectNeighbors(candidates, idx.m)
+
+		idx.nodes[nodeIdx].neighbors[l] = neighbors
+		for _, n := range neighbors {
+			idx.connect(n, nodeIdx, l)
+		}
+		if len(candidates) > 0 {
+			current = candidates[0].idx
+		}
+	}
+
+	if level > idx.maxLevel {
+		idx.maxLevel = level
+		idx.entry = nodeIdx
+	}
+}
+
+// connect adds a bidirectional edge, trimming the neighbor list back to M
+// entries (keeping the closest) if it grows past the bound.
+func (idx *ConceptIndex) connect(a, b, layer int) {
+	for len(idx.nodes[a].neighbors) <= layer {
+		idx.nodes[a].neighbors = append(idx.nodes[a].neighbors, nil)
+	}
+	idx.nodes[a].neighbors[layer] = append(idx.nodes[a].neighbors[layer], b)
+
+	if len(idx.nodes[a].neighbors[layer]) > idx.m {
+		vec := idx.concepts[a].Embedding
+		cands := make([]scored, 0, len(idx.nodes[a].neighbors[layer]))
+		for _, n := range idx.nodes[a].neighbors[layer] {
+			cands = append(cands, scored{idx: n, score: CosineSimilarity(vec, idx.concepts[n].Embedding)})
+		}
+		sort.Slice(cands, func(i, j int) bool { return cands[i].score > cands[j].score })
+		trimmed := make([]int, 0, idx.m)
+		for i := 0; i < idx.m && i < len(cands); i++ {
+			trimmed = append(trimmed, cands[i].idx)
+		}
+		idx.nodes[a].neighbors[layer] = trimmed
+	}
+}
+
+type scored struct {
+	idx   int
+	score float32 // cosine similarity; higher is closer
+}
+
+// greedyDescend walks from current to its locally-closest neighbor at layer,
+// repeating until no neighbor improves on current. Used to find a good entry
+// point before switching to a wider beam search one layer down.
+func (idx *ConceptIndex) greedyDescend(current int, vec types.Embedding, layer int) int {
+	best := current
+	bestScore := CosineSimilarity(vec, idx.concepts[current].Embedding)
+
+	for {
+		improved := false
+		for _, n := range idx.layerNeighbors(best, layer) {
+			s := CosineSimilarity(vec, idx.concepts[n].Embedding)
+			if s > bestScore {
+				bestScore = s
+				best = n
+				improved = true
+			}
+		}
+		if !improved {
+			return best
+		}
+	}
+}
+
+func (idx *ConceptIndex) layerNeighbors(node, layer int) []int {
+	if layer >= len(idx.nodes[node].neighbors) {
+		return nil
+	}
+	return idx.nodes[node].neighbors[layer]
+}
+
+// searchLayer runs a bounded best-first search at one layer, returning up to
+// ef candidates sorted by descending cosine similarity.
+func (idx *ConceptIndex) searchLayer(vec types.Embedding, entry int, ef int, layer int) []scored {
+	visited := map[int]bool{entry: true}
+	candidateScore := CosineSimilarity(vec, idx.concepts[entry].Embedding)
+	candidates := []scored{{idx: entry, score: candidateScore}}
+	results := []scored{{idx: entry, score: candidateScore}}
+
+	for len(candidates) > 0 {
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+		c := candidates[0]
+		candidates = candidates[1:]
+
+		sort.Slice(results, func(i, j int) bool { return results[i].score > results[j].score })
+		if len(results) >= ef && c.score < results[len(results)-1].score {
+			break
+		}
+
+		for _, n := range idx.layerNeighbors(c.idx, layer) {
+			if visited[n] {
+				continue
+			}
+			visited[n] = true
+			s := CosineSimilarity(vec, idx.concepts[n].Embedding)
+			candidates = append(candidates, scored{idx: n, score: s})
+			results = append(results, scored{idx: n, score: s})
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool { return results[i].score > results[j].score })
+	if len(results) > ef {
+		results = results[:ef]
+	}
+	return results
+}
+
+// selectNeighbors keeps the closest m candidates by score.
+func selectNeighbors(candidates []scored, m int) []int {
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	neighbors := make([]int, 0, m)
+	for i := 0; i < m && i < len(candidates); i++ {
+		neighbors = append(neighbors, candidates[i].idx)
+	}
+	return neighbors
+}
+
+// Query returns the k concepts nearest to vec, searching with beam width
+// efSearch. efSearch should be >= k; a wider beam trades latency for recall.
+func (idx *ConceptIndex) Query(vec types.Embedding, k int, efSearch int) []types.ConceptMatch {
+	if idx.entry == -1 {
+		return nil
+	}
+	if efSearch < k {
+		efSearch = k
+	}
+
+	current := idx.entry
+	for l := idx.maxLevel; l > 0; l-- {
+		current = idx.greedyDescend(current, vec, l)
+	}
+
+	candidates := idx.searchLayer(vec, current, efSearch, 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+
+	matches := make([]types.ConceptMatch, 0, len(candidates))
+	for _, c := range candidates {
+		matches = append(matches, types.ConceptMatch{
+			Concept:         idx.concepts[c.idx].Name,
+			SimilarityScore: c.score,
+		})
+	}
+	return matches
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,55 @@
+package embedding_test
+
+import (
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+func TestConceptIndexQueryFindsExactMatch(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Embedding: types.Embedding{Vector: []float32{1, 0, 0}}},
+		{Name: "locked", Embedding: types.Embedding{Vector: []float32{0, 1, 0}}},
+		{Name: "admin", Embedding: types.Embedding{Vector: []float32{0, 0, 1}}},
+	}
+
+	idx := embedding.NewConceptIndex(42)
+	idx.Build(concepts)
+
+	matches := idx.Query(types.Embedding{Vector: []float32{0, 0.9, 0.1}}, 1, 10)
+	if len(matches) != 1 {
+		t.Fatalf("expected 1 match, got %d", len(matches))
+	}
+	if matches[0].Concept != "locked" {
+		t.Errorf("expected closest concept to be 'locked', got %q", matches[0].Concept)
+	}
+}
+
+func TestConceptIndexDeterministicAcrossBuilds(t *testing.T) {
+	concepts := make([]types.SecurityConcept, 0, 50)
+	for i := 0; i < 50; i++ {
+		concepts = append(concepts, types.SecurityConcept{
+			Name:      string(rune('a' + i%26)),
+			Embedding: types.Embedding{Vector: []float32{float32(i), float32(50 - i), float32(i % 7)}},
+		})
+	}
+
+	idx1 := embedding.NewConceptIndex(7)
+	idx1.Build(concepts)
+	idx2 := embedding.NewConceptIndex(7)
+	idx2.Build(concepts)
+
+	query := types.Embedding{Vector: []float32{10, 40, 3}}
+	m1 := idx1.Query(query, 5, 20)
+	m2 := idx2.Query(query, 5, 20)
+
+	if len(m1) != len(m2) {
+		t.Fatalf("expected same result size for same seed, got %d vs %d", len(m1), len(m2))
+	}
+	for i := range m1 {
+		if m1[i].Concept != m2[i].Concept {
+			t.Errorf("expected same build with same seed to produce same ranking at %d: %q vs %q", i, m1[i].Concept, m2[i].Concept)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package embedding
+
+import (
+	"math"
+	"strings"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// LocalEmbedder produces embeddings without making a network call, so that
+// offline matching doesn't depend on degrading to a byte-hash stub.
+// HashingEmbedder and TFIDFHashingEmbedder below need no model file;
+// GGUFEmbedder (gguf.go) reads a GGUF model's token embedding table from disk.
+type LocalEmbedder interface {
+	// Embed computes a vector for text. It never fails: a local embedder has
+	// no network or model-loading step that can error out at call time.
+	Embed(text string) types.Embedding
+	// ModelID identifies the backend, stored alongside cached vectors so
+	// caches computed by different backends never get compared.
+	ModelID() string
+}
+
+// hashingModelID is the model_id stamped on vectors produced by HashingEmbedder.
+const hashingModelID = "local-subword-hashing-v1"
+
+// HashingEmbedder is a fastText-style local embedder: it hashes character
+// n-grams (subwords) of the input into a fixed-size vector, so that names
+// sharing subwords (is_locked, locked, unlocked) land close together in
+// cosine space even without a trained model.
+type HashingEmbedder struct {
+	Dimension int
+	MinN      int
+	MaxN      int
+}
+
+// NewHashingEmbedder creates a HashingEmbedder with the given vector size.
+// dim 64 is a reasonable default: large enough to avoid excessive hash
+// collisions for typical identifier lengths, small enough to stay cheap.
+func NewHashingEmbedder(dim int) *HashingEmbedder {
+	if dim <= 0 {
+		dim = 64
+	}
+	return &HashingEmbedder{Dimension: dim, MinN: 3, MaxN: 5}
+}
+
+// ModelID implements LocalEmbedder.
+func (h *HashingEmbedder) ModelID() string {
+	return hashingModelID
+}
+
+// Embed implements LocalEmbedder using subword hashing.
+func (h *HashingEmbedder) Embed(text string) types.Embedding {
+	vector := make([]float32, h.Dimension)
+	normalized := "<" + strings.ToLower(text) + ">"
+
+	for n := h.MinN; n <= h.MaxN; n++ {
+		for _, gram := range ngrams(normalized, n) {
+			idx := fnv32(gram) % uint32(h.Dimension)
+			vector[idx] += 1.0
+		}
+	}
+
+	normalize(vector)
+	return types.Embedding{Vector: vector, ModelID: h.ModelID()}
+}
+
+// ngrams returns all contiguous substrings of length n in s.
+func ngrams(s string, n int) []string {
+	runes := []rune(s)
+	if len(runes) < n {
+		return nil
+	}
+	grams := make([]string, 0, len(runes)-n+1)
+	for i := 0; i+n <= len(runes); i++ {
+		grams = append(grams, string(runes[i:i+n]))
+	}
+	return grams
+}
+
+// fnv32 is a small, dependency-free string hash (FNV-1a).
+func fnv32(s string) uint32 {
+	const (
+		offset32 = 2166136261
+		prime32  = 16777619
+	)
+	hash := uint32(offset32)
+	for i := 0; i < len(s); i++ {
+		hash ^= uint32(s[i])
+		hash *= prime32
+	}
+	return hash
+}
+
+// normalize scales vector to unit length in place.
+func normalize(vector []float32) {
+	var sumSquares float64
+	for _, v := range vector {
+		sumSquares += float64(v) * float64(v)
+	}
+	if sumSquares == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sumSquares))
+	for i := range vector {
+		vector[i] /= norm
+	}
+}
+
+// tfidfHashingModelID is the model_id stamped on vectors produced by TFIDFHashingEmbedder.
+const tfidfHashingModelID = "local-tfidf-hashing-v1"
+
+// TFIDFHashingEmbedder improves on HashingEmbedder by weighting each
+// hashed n-gram bucket by inverse document frequency, computed once from a
+// fixed corpus (typically the security concepts' names/descriptions/
+// synonyms) rather than treating every subword as equally informative.
+// Common n-grams shared by almost every concept ("_id", "the") contribute
+// little; n-grams that appear in only one or two concepts dominate the
+// resulting vector.
+type TFIDFHashingEmbedder struct {
+	Dimension int
+	MinN      int
+	MaxN      int
+	idf       []float32
+}
+
+// NewTFIDFHashingEmbedder builds a TF-IDF hashing embedder whose IDF weights
+// are fit from corpus, a set of documents (e.g. one per security concept).
+func NewTFIDFHashingEmbedder(dim int, corpus []string) *TFIDFHashingEmbedder {
+	if dim <= 0 {
+		dim = 64
+	}
+	h := &TFIDFHashingEmbedder{Dimension: dim, MinN: 3, MaxN: 5}
+	h.idf = h.fitIDF(corpus)
+	return h
+}
+
+// fitIDF computes, per hash bucket, log((N+1)/(df+1)) + 1 over corpus, the
+// standard smoothed IDF formula: every bucket gets a strictly positive
+// weight even if it appeared in every document or in none.
+func (h *TFIDFHashingEmbedder) fitIDF(corpus []string) []float32 {
+	df := make([]int, h.Dimension)
+	for _, doc := range corpus {
+		seen := make(map[uint32]bool)
+		normalized := "<" + strings.ToLower(doc) + ">"
+		for n := h.MinN; n <= h.MaxN; n++ {
+			for _, gram := range ngrams(normalized, n) {
+				seen[fnv32(gram)%uint32(h.Dimension)] = true
+			}
+		}
+		for idx := range seen {
+			df[idx]++
+		}
+	}
+
+	idf := make([]float32, h.Dimension)
+	n := float64(len(corpus))
+	for i, count := range df {
+		idf[i] = float32(math.Log((n+1)/(float64(count)+1)) + 1)
+	}
+	return idf
+}
+
+// ModelID implements LocalEmbedder.
+func (h *TFIDFHashingEmbedder) ModelID() string {
+	return tfidfHashingModelID
+}
+
+// Embed implements LocalEmbedder using IDF-weighted subword hashing.
+func (h *TFIDFHashingEmbedder) Embed(text string) types.Embedding {
+	vector := make([]float32, h.Dimension)
+	normalized := "<" + strings.ToLower(text) + ">"
+
+	for n := h.MinN; n <= h.MaxN; n++ {
+		for _, gram := range ngrams(normalized, n) {
+			idx := fnv32(gram) % uint32(h.Dimension)
+			vector[idx] += 1.0
+		}
+	}
+
+	for i := range vector {
+		if i < len(h.idf) {
+			vector[i] *= h.idf[i]
+		}
+	}
+
+	normalize(vector)
+	return types.Embedding{Vector: vector, ModelID: h.ModelID()}
+}
+
+// ConceptCorpus builds the text corpus TFIDFHashingEmbedder fits its IDF
+// weights against: one document per concept, combining its name,
+// description, and synonyms.
+func ConceptCorpus(concepts []types.SecurityConcept) []string {
+	corpus := make([]string, len(concepts))
+	for i, c := range concepts {
+		doc := c.Name + " " + c.Description
+		for _, syn := range c.Synonyms {
+			doc += " " + syn
+		}
+		corpus[i] = doc
+	}
+	return corpus
+}
@@ -0,0 +1,81 @@
+package embedding_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+func TestHashingEmbedderDeterministic(t *testing.T) {
+	h := embedding.NewHashingEmbedder(32)
+
+	a := h.Embed("is_locked")
+	b := h.Embed("is_locked")
+
+	if len(a.Vector) != 32 {
+		t.Fatalf("expected vector of length 32, got %d", len(a.Vector))
+	}
+
+	for i := range a.Vector {
+		if a.Vector[i] != b.Vector[i] {
+			t.Errorf("expected deterministic output, vector[%d] differed: %f != %f", i, a.Vector[i], b.Vector[i])
+		}
+	}
+
+	if a.ModelID == "" {
+		t.Errorf("expected ModelID to be set")
+	}
+}
+
+func TestHashingEmbedderSimilarNames(t *testing.T) {
+	h := embedding.NewHashingEmbedder(64)
+
+	locked := h.Embed("locked")
+	isLocked := h.Embed("is_locked")
+	unrelated := h.Embed("donation_cap")
+
+	simRelated := embedding.CosineSimilarity(locked, isLocked)
+	simUnrelated := embedding.CosineSimilarity(locked, unrelated)
+
+	if simRelated <= simUnrelated {
+		t.Errorf("expected 'locked' to be more similar to 'is_locked' (%f) than to 'donation_cap' (%f)", simRelated, simUnrelated)
+	}
+}
+
+func TestTFIDFHashingEmbedderDeterministic(t *testing.T) {
+	corpus := []string{"reentrancy guard is_locked", "donation cap donation_cap", "owner admin access_control"}
+	h := embedding.NewTFIDFHashingEmbedder(32, corpus)
+
+	a := h.Embed("is_locked")
+	b := h.Embed("is_locked")
+
+	if len(a.Vector) != 32 {
+		t.Fatalf("expected vector of length 32, got %d", len(a.Vector))
+	}
+	for i := range a.Vector {
+		if a.Vector[i] != b.Vector[i] {
+			t.Errorf("expected deterministic output, vector[%d] differed: %f != %f", i, a.Vector[i], b.Vector[i])
+		}
+	}
+	if a.ModelID == "" {
+		t.Errorf("expected ModelID to be set")
+	}
+}
+
+func TestConceptCorpusCombinesNameDescriptionSynonyms(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "locked", Description: "reentrancy guard", Synonyms: []string{"mutex", "is_locked"}},
+	}
+	corpus := embedding.ConceptCorpus(concepts)
+	if len(corpus) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(corpus))
+	}
+	doc := corpus[0]
+	for _, want := range []string{"locked", "reentrancy guard", "mutex", "is_locked"} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("expected corpus document to contain %q, got %q", want, doc)
+		}
+	}
+}
@@ -7,73 +7,141 @@ import (
 	"github.com/jeffrydegrande/solidair/types"
 )
 
+// MatchMode selects which signal EmbeddingMatcher.MatchVariable scores
+// concepts with.
+type MatchMode int
+
+const (
+	// SemanticOnly ranks concepts by cosine similarity between embeddings,
+	// the matcher's original behavior.
+	SemanticOnly MatchMode = iota
+	// LexicalOnly ranks concepts by string similarity against the variable
+	// name (exact/substring match on the concept name or a synonym).
+	LexicalOnly
+	// Hybrid scores concepts under both scorers, then blends the two scores
+	// as a SemanticRatio-weighted average (see fuseWeighted).
+	Hybrid
+)
+
+// defaultSemanticRatio weights semantic and lexical scores equally in Hybrid
+// mode when neither the matcher nor the concept specifies one.
+const defaultSemanticRatio = 0.5
+
 // EmbeddingMatcher is a system for matching variables to security concepts
 type EmbeddingMatcher struct {
-	OpenAI              *OpenAIClient
-	Concepts            []types.SecurityConcept
-	Cache               *EmbeddingCache
+	Provider EmbeddingProvider
+	Local    LocalEmbedder
+	Concepts []types.SecurityConcept
+	Cache    *EmbeddingCache
+	// SimilarityThreshold is compared against the score MatchVariable
+	// produces: cosine similarity for SemanticOnly, string similarity in
+	// [0,1] for LexicalOnly, and the SemanticRatio-weighted blend of the two
+	// for Hybrid.
 	SimilarityThreshold float32
 	Offline             bool
+	Mode                MatchMode
+	// SemanticRatio weights Hybrid mode's fused score: fused = ratio*cosine +
+	// (1-ratio)*lexical. 0 is pure-lexical, 1 is pure-semantic. Set by
+	// NewEmbeddingMatcher to defaultSemanticRatio; a concept with its own
+	// SecurityConcept.SemanticRatio overrides this for matches against that
+	// concept.
+	SemanticRatio float32
+
+	// index is an approximate nearest-neighbor index over Concepts, built by
+	// NewEmbeddingMatcher when len(Concepts) exceeds ExactScanThreshold. Below that size
+	// the linear cosine scan in MatchVariable is cheaper than building one.
+	index *ConceptIndex
 }
 
-// NewEmbeddingMatcher creates a new matcher with the provided OpenAI client and concepts
-func NewEmbeddingMatcher(client *OpenAIClient, concepts []types.SecurityConcept, offline bool) *EmbeddingMatcher {
-	return &EmbeddingMatcher{
-		OpenAI:              client,
+// NewEmbeddingMatcher creates a new matcher with the provided embedding
+// provider and concepts. provider is ignored when offline is true; local is
+// the embedder used in that case and defaults to HashingEmbedder when nil.
+func NewEmbeddingMatcher(provider EmbeddingProvider, concepts []types.SecurityConcept, offline bool, local LocalEmbedder) *EmbeddingMatcher {
+	if local == nil {
+		local = NewHashingEmbedder(64)
+	}
+	m := &EmbeddingMatcher{
+		Provider:            provider,
+		Local:               local,
 		Concepts:            concepts,
 		Cache:               &EmbeddingCache{Variables: make(map[string]types.Embedding)},
 		SimilarityThreshold: 0.7, // Default threshold
 		Offline:             offline,
+		SemanticRatio:       defaultSemanticRatio,
 	}
+	if len(concepts) > ExactScanThreshold {
+		m.index = NewConceptIndex(0)
+		m.index.Build(concepts)
+	}
+	return m
 }
 
-// GetVariableEmbedding gets the embedding for a variable, using cache if available
+// GetVariableEmbedding gets the embedding for a variable, using cache if
+// available. It embeds variable.Prompt() rather than its bare Name, so the
+// vector reflects what kind of declaration the variable is (storage field,
+// parameter, local, constant) instead of just an identifier that could mean
+// anything out of context.
 func (m *EmbeddingMatcher) GetVariableEmbedding(ctx context.Context, variable types.VariableInfo) (types.Embedding, error) {
+	return m.embedCached(ctx, variable.Prompt())
+}
+
+// embedCached embeds text, consulting and populating the variable cache
+// first. It's shared by variable-name embedding and chunk embedding so both
+// paths get the same offline/online routing and caching behavior.
+func (m *EmbeddingMatcher) embedCached(ctx context.Context, text string) (types.Embedding, error) {
 	// Check if we have a cached embedding
-	if embedding, ok := m.Cache.Variables[variable.Name]; ok {
+	if embedding, ok := m.Cache.Variables[text]; ok {
 		return embedding, nil
 	}
 
-	// If we're in offline mode, use a simple fallback method
+	// If we're in offline mode, route through the local embedder instead of
+	// paying for an OpenAI round-trip per variable
 	if m.Offline {
-		return m.getOfflineEmbedding(variable.Name), nil
+		embedding := m.Local.Embed(text)
+		m.Cache.Variables[text] = embedding
+		return embedding, nil
 	}
 
-	// Get embedding from OpenAI
-	embedding, err := m.OpenAI.GetEmbedding(ctx, variable.Name)
+	// Get embedding from the configured provider
+	embeddings, err := m.Provider.Embed(ctx, []string{text})
 	if err != nil {
 		return types.Embedding{}, err
 	}
 
 	// Cache the embedding
-	m.Cache.Variables[variable.Name] = embedding
+	m.Cache.Variables[text] = embeddings[0]
 
-	return embedding, nil
+	return embeddings[0], nil
 }
 
-// getOfflineEmbedding creates a simple embedding for offline mode
-// This is a placeholder - in a real implementation, we'd use a more
-// sophisticated method for generating offline embeddings
-func (m *EmbeddingMatcher) getOfflineEmbedding(name string) types.Embedding {
-	// Create a simple embedding based on string characteristics
-	// This is just a placeholder that creates a vector with a few dimensions
-	vector := make([]float32, 3)
-
-	// Fill with some values based on the string
-	for i := 0; i < len(vector); i++ {
-		if i < len(name) {
-			vector[i] = float32(name[i%len(name)]) / 255.0
-		} else {
-			vector[i] = 0
+// MatchVariable finds the best matching security concept for a variable,
+// scored according to m.Mode.
+func (m *EmbeddingMatcher) MatchVariable(ctx context.Context, variable types.VariableInfo) ([]types.ConceptMatch, error) {
+	switch m.Mode {
+	case LexicalOnly:
+		matches := m.rankLexical(variable)
+		return filterByThreshold(matches, m.SimilarityThreshold), nil
+	case Hybrid:
+		semantic, err := m.rankSemantic(ctx, variable)
+		if err != nil {
+			return nil, err
+		}
+		lexical := m.rankLexical(variable)
+		fused := m.fuseWeighted(semantic, lexical)
+		return filterByThreshold(fused, m.SimilarityThreshold), nil
+	default:
+		semantic, err := m.rankSemantic(ctx, variable)
+		if err != nil {
+			return nil, err
 		}
+		return filterByThreshold(semantic, m.SimilarityThreshold), nil
 	}
-
-	return types.Embedding{Vector: vector}
 }
 
-// MatchVariable finds the best matching security concept for a variable
-func (m *EmbeddingMatcher) MatchVariable(ctx context.Context, variable types.VariableInfo) ([]types.ConceptMatch, error) {
-	// Get embedding for the variable
+// rankSemantic scores every concept by cosine similarity to the variable's
+// embedding, sorted best-first. No threshold is applied here so Hybrid can
+// fuse over the full ranking.
+func (m *EmbeddingMatcher) rankSemantic(ctx context.Context, variable types.VariableInfo) ([]types.ConceptMatch, error) {
 	varEmbedding, err := m.GetVariableEmbedding(ctx, variable)
 	if err != nil {
 		return nil, err
@@ -81,30 +149,119 @@ func (m *EmbeddingMatcher) MatchVariable(ctx context.Context, variable types.Var
 
 	var matches []types.ConceptMatch
 
-	// Compare with each concept
-	for _, concept := range m.Concepts {
-		similarity := CosineSimilarity(varEmbedding, concept.Embedding)
-
-		// If similarity is above threshold, add to matches
-		if similarity >= m.SimilarityThreshold {
+	if m.index != nil {
+		// Large concept sets: query the ANN index instead of scanning every
+		// concept for every variable. The index is built once from
+		// m.Concepts' default Embedding (see ConceptIndex.Build), so unlike
+		// the exact scan below it assumes every concept's default vector
+		// shares one embedding space rather than picking per-embedder.
+		matches = m.index.Query(varEmbedding, len(m.Concepts), defaultEfSearch)
+		for i := range matches {
+			matches[i].Variable = variable
+		}
+	} else {
+		// Small concept sets: the exact linear scan is cheap and avoids any
+		// approximation error. Each concept may carry vectors from several
+		// embedders side by side (SecurityConcept.Embeddings); EmbeddingFor
+		// picks the one produced by the same embedder as varEmbedding, and a
+		// concept with no vector from that embedder is skipped rather than
+		// compared against a mismatched one.
+		for _, concept := range m.Concepts {
+			conceptEmbedding, ok := concept.EmbeddingFor(varEmbedding.ModelID)
+			if !ok {
+				continue
+			}
+			score, err := CosineSimilarityChecked(varEmbedding, conceptEmbedding)
+			if err != nil {
+				continue // defensive: EmbeddingFor already guarantees a ModelID match
+			}
 			matches = append(matches, types.ConceptMatch{
 				Variable:        variable,
 				Concept:         concept.Name,
-				SimilarityScore: similarity,
+				SimilarityScore: score,
 			})
 		}
 	}
 
-	// Sort matches by similarity score (highest first)
+	sortBySimilarityDesc(matches)
+	return matches, nil
+}
+
+// rankLexical scores every concept by string similarity to the variable
+// name, sorted best-first.
+func (m *EmbeddingMatcher) rankLexical(variable types.VariableInfo) []types.ConceptMatch {
+	matches := make([]types.ConceptMatch, len(m.Concepts))
+	for i, concept := range m.Concepts {
+		matches[i] = types.ConceptMatch{
+			Variable:        variable,
+			Concept:         concept.Name,
+			SimilarityScore: calculateStringSimilarity(variable.Name, concept.Name, concept.Synonyms),
+		}
+	}
+	sortBySimilarityDesc(matches)
+	return matches
+}
+
+// fuseWeighted combines a semantic and a lexical ranking into one score per
+// concept: ratio*cosine + (1-ratio)*lexical, where ratio is m.SemanticRatio
+// unless the concept itself sets SemanticRatio, in which case that overrides
+// it. Unlike a rank-based fusion, this keeps the fused score on the same
+// [0,1] scale as the inputs, so SimilarityThreshold means the same thing
+// across match modes.
+func (m *EmbeddingMatcher) fuseWeighted(semantic, lexical []types.ConceptMatch) []types.ConceptMatch {
+	ratios := make(map[string]float32, len(m.Concepts))
+	for _, c := range m.Concepts {
+		if c.SemanticRatio != nil {
+			ratios[c.Name] = *c.SemanticRatio
+		} else {
+			ratios[c.Name] = m.SemanticRatio
+		}
+	}
+
+	lexScores := make(map[string]float32, len(lexical))
+	var variable types.VariableInfo
+	for _, match := range lexical {
+		lexScores[match.Concept] = match.SimilarityScore
+		variable = match.Variable
+	}
+
+	fused := make([]types.ConceptMatch, 0, len(semantic))
+	for _, match := range semantic {
+		ratio := ratios[match.Concept]
+		fused = append(fused, types.ConceptMatch{
+			Variable:        variable,
+			Concept:         match.Concept,
+			SimilarityScore: ratio*match.SimilarityScore + (1-ratio)*lexScores[match.Concept],
+		})
+	}
+	sortBySimilarityDesc(fused)
+	return fused
+}
+
+func filterByThreshold(matches []types.ConceptMatch, threshold float32) []types.ConceptMatch {
+	var filtered []types.ConceptMatch
+	for _, match := range matches {
+		if match.SimilarityScore >= threshold {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered
+}
+
+func sortBySimilarityDesc(matches []types.ConceptMatch) {
 	sort.Slice(matches, func(i, j int) bool {
 		return matches[i].SimilarityScore > matches[j].SimilarityScore
 	})
-
-	return matches, nil
 }
 
-// MatchVariables matches multiple variables to security concepts
+// MatchVariables matches multiple variables to security concepts. It first
+// embeds every not-yet-cached variable Prompt() in a single batched provider
+// call, rather than issuing one HTTP request per variable.
 func (m *EmbeddingMatcher) MatchVariables(ctx context.Context, vars []types.VariableInfo) (map[string][]types.ConceptMatch, error) {
+	if err := m.warmCache(ctx, vars); err != nil {
+		return nil, err
+	}
+
 	result := make(map[string][]types.ConceptMatch)
 
 	for _, variable := range vars {
@@ -122,6 +279,34 @@ func (m *EmbeddingMatcher) MatchVariables(ctx context.Context, vars []types.Vari
 	return result, nil
 }
 
+// warmCache embeds every variable's Prompt() not already cached, in as few
+// provider calls as possible. Offline mode embeds locally (no batching
+// benefit there), so it's left to embedCached/GetVariableEmbedding.
+func (m *EmbeddingMatcher) warmCache(ctx context.Context, vars []types.VariableInfo) error {
+	if m.Offline {
+		return nil
+	}
+
+	var uncached []string
+	for _, variable := range vars {
+		if _, ok := m.Cache.Variables[variable.Prompt()]; !ok {
+			uncached = append(uncached, variable.Prompt())
+		}
+	}
+	if len(uncached) == 0 {
+		return nil
+	}
+
+	embeddings, err := m.Provider.Embed(ctx, uncached)
+	if err != nil {
+		return err
+	}
+	for i, prompt := range uncached {
+		m.Cache.Variables[prompt] = embeddings[i]
+	}
+	return nil
+}
+
 // calculateStringSimilarity computes a simple similarity score for offline mode
 func calculateStringSimilarity(varName, conceptName string, synonyms []string) float32 {
 	// Check for exact match
@@ -144,4 +329,3 @@ func calculateStringSimilarity(varName, conceptName string, synonyms []string) f
 	// Simple n-gram similarity for fallback
 	return CalculateNGramSimilarity(varName, conceptName)
 }
-
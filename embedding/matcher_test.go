@@ -19,7 +19,7 @@ func TestNewEmbeddingMatcher(t *testing.T) {
 	}
 	
 	// Test with nil client (offline mode)
-	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true)
+	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true, nil)
 	if matcher == nil {
 		t.Errorf("NewEmbeddingMatcher() returned nil")
 	}
@@ -49,7 +49,7 @@ func TestNewEmbeddingMatcher(t *testing.T) {
 func TestGetVariableEmbedding(t *testing.T) {
 	// Create a matcher in offline mode
 	concepts := []types.SecurityConcept{}
-	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true)
+	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true, nil)
 	
 	// Test variable
 	variable := types.VariableInfo{
@@ -73,7 +73,7 @@ func TestGetVariableEmbedding(t *testing.T) {
 	
 	// Test caching behavior - modify the cache directly
 	customVector := []float32{0.9, 0.9, 0.9}
-	matcher.Cache.Variables[variable.Name] = types.Embedding{Vector: customVector}
+	matcher.Cache.Variables[variable.Prompt()] = types.Embedding{Vector: customVector}
 	
 	// Get the embedding again - should use cached value
 	cachedEmb, err := matcher.GetVariableEmbedding(ctx, variable)
@@ -115,24 +115,26 @@ func TestMatchVariables(t *testing.T) {
 	}
 	
 	// Create a matcher in offline mode with test concepts
-	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true)
+	matcher := embedding.NewEmbeddingMatcher(nil, concepts, true, nil)
 	
 	// Override similarity threshold for testing
 	matcher.SimilarityThreshold = 0.5
 	
-	// Prepare the cache with known vectors to ensure predictable matching
-	matcher.Cache.Variables = map[string]types.Embedding{
-		"is_active": {Vector: []float32{0.9, 0.1, 0.0}},    // Should match "active"
-		"is_locked": {Vector: []float32{0.1, 0.9, 0.0}},    // Should match "locked"
-		"other_var": {Vector: []float32{0.3, 0.3, 0.3}},    // Should not match anything
-	}
-	
 	// Create test variables
 	variables := []types.VariableInfo{
 		{Name: "is_active", Type: "bool", Context: "variable"},
 		{Name: "is_locked", Type: "bool", Context: "variable"},
 		{Name: "other_var", Type: "int", Context: "variable"},
 	}
+
+	// Prepare the cache with known vectors, keyed by each variable's
+	// Prompt() (what GetVariableEmbedding actually caches under), to ensure
+	// predictable matching
+	matcher.Cache.Variables = map[string]types.Embedding{
+		variables[0].Prompt(): {Vector: []float32{0.9, 0.1, 0.0}}, // Should match "active"
+		variables[1].Prompt(): {Vector: []float32{0.1, 0.9, 0.0}}, // Should match "locked"
+		variables[2].Prompt(): {Vector: []float32{0.3, 0.3, 0.3}}, // Weak match (cosine ~0.58) to both concepts
+	}
 	
 	// Match variables to concepts
 	ctx := context.Background()
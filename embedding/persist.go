@@ -0,0 +1,298 @@
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// Manifest records what's stored in a persisted concept index: the
+// embedding model, vector dimension, and a content hash per concept so a
+// later load can tell which concepts are stale without re-embedding
+// everything to find out.
+type Manifest struct {
+	ModelID   string                 `json:"model_id"`
+	Dimension int                    `json:"dimension"`
+	Concepts  []ConceptManifestEntry `json:"concepts"`
+}
+
+// ConceptManifestEntry is one concept's position in the vectors file plus
+// the hash used to detect edits to its name/description/synonyms.
+type ConceptManifestEntry struct {
+	Name        string `json:"name"`
+	ContentHash string `json:"content_hash"`
+	Offset      int64  `json:"offset"` // byte offset into the vectors file
+}
+
+// conceptContentHash hashes the fields that determine what a concept's
+// embedding should be, so a manifest entry can detect edits without storing
+// the whole concept.
+func conceptContentHash(c types.SecurityConcept) string {
+	h := sha256.New()
+	h.Write([]byte(c.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(c.Description))
+	for _, syn := range c.Synonyms {
+		h.Write([]byte{0})
+		h.Write([]byte(syn))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// vectorsPath and manifestPath derive the two files a persisted index is
+// split across from a single base path.
+func vectorsPath(basePath string) string  { return basePath + ".vectors" }
+func manifestPath(basePath string) string { return basePath + ".manifest.json" }
+
+// SaveIndex persists concepts' embeddings to basePath as a fixed-stride
+// binary vectors file (dimension*4 bytes per concept, one after another —
+// a layout that could be mmap'd directly rather than deserialized) plus a
+// JSON manifest recording the model, dimension, and a content hash per
+// concept for change detection on the next load.
+func SaveIndex(basePath string, concepts []types.SecurityConcept, modelID string) error {
+	dimension := 0
+	if len(concepts) > 0 {
+		dimension = len(concepts[0].Embedding.Vector)
+	}
+
+	file, err := os.Create(vectorsPath(basePath))
+	if err != nil {
+		return fmt.Errorf("error creating vectors file: %w", err)
+	}
+	defer file.Close()
+
+	entries := make([]ConceptManifestEntry, len(concepts))
+	var offset int64
+	for i, concept := range concepts {
+		if len(concept.Embedding.Vector) != dimension {
+			return fmt.Errorf("concept %q has vector dimension %d, want %d", concept.Name, len(concept.Embedding.Vector), dimension)
+		}
+		if err := binary.Write(file, binary.LittleEndian, concept.Embedding.Vector); err != nil {
+			return fmt.Errorf("error writing vector for %q: %w", concept.Name, err)
+		}
+		entries[i] = ConceptManifestEntry{
+			Name:        concept.Name,
+			ContentHash: conceptContentHash(concept),
+			Offset:      offset,
+		}
+		offset += int64(dimension) * 4
+	}
+
+	manifest := Manifest{ModelID: modelID, Dimension: dimension, Concepts: entries}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding manifest: %w", err)
+	}
+	if err := os.WriteFile(manifestPath(basePath), data, 0644); err != nil {
+		return fmt.Errorf("error writing manifest: %w", err)
+	}
+	return nil
+}
+
+// LoadIndex reads a manifest and its vectors file back into a
+// name -> embedding map; it doesn't know concept Description/Synonyms,
+// since those live in the concept definitions, not the index.
+func LoadIndex(basePath string) (Manifest, map[string]types.Embedding, error) {
+	manifestData, err := os.ReadFile(manifestPath(basePath))
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return Manifest{}, nil, fmt.Errorf("error parsing manifest: %w", err)
+	}
+
+	file, err := os.Open(vectorsPath(basePath))
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("error opening vectors file: %w", err)
+	}
+	defer file.Close()
+
+	vectors := make(map[string]types.Embedding, len(manifest.Concepts))
+	for _, entry := range manifest.Concepts {
+		vector := make([]float32, manifest.Dimension)
+		if _, err := file.Seek(entry.Offset, 0); err != nil {
+			return Manifest{}, nil, fmt.Errorf("error seeking to %q's vector: %w", entry.Name, err)
+		}
+		if err := binary.Read(file, binary.LittleEndian, vector); err != nil {
+			return Manifest{}, nil, fmt.Errorf("error reading %q's vector: %w", entry.Name, err)
+		}
+		vectors[entry.Name] = types.Embedding{Vector: vector, ModelID: manifest.ModelID}
+	}
+
+	return manifest, vectors, nil
+}
+
+// SyncConcepts brings concepts' embeddings up to date against a persisted
+// index at basePath: concepts whose content hash matches the manifest reuse
+// their stored vector, and only new or edited concepts are re-embedded
+// through provider. The refreshed index is saved back to basePath before
+// returning, so the next call pays for nothing but the diff.
+func SyncConcepts(ctx context.Context, provider EmbeddingProvider, concepts []types.SecurityConcept, basePath string) ([]types.SecurityConcept, error) {
+	var (
+		priorHashes  map[string]string
+		priorVectors map[string]types.Embedding
+	)
+	if manifest, vectors, err := LoadIndex(basePath); err == nil {
+		if manifest.ModelID == provider.ID() && manifest.Dimension == provider.Dimension() {
+			priorHashes = make(map[string]string, len(manifest.Concepts))
+			for _, entry := range manifest.Concepts {
+				priorHashes[entry.Name] = entry.ContentHash
+			}
+			priorVectors = vectors
+		}
+		// A manifest stamped with a different model or dimension belongs to a
+		// different embedding space: every vector in it is stale, so skip
+		// straight to re-embedding everything rather than comparing hashes.
+	}
+
+	var stale []int
+	result := make([]types.SecurityConcept, len(concepts))
+	copy(result, concepts)
+
+	for i, concept := range result {
+		hash := conceptContentHash(concept)
+		if priorHashes != nil && priorHashes[concept.Name] == hash {
+			if vector, ok := priorVectors[concept.Name]; ok {
+				result[i].Embedding = vector
+				continue
+			}
+		}
+		stale = append(stale, i)
+	}
+
+	if len(stale) > 0 {
+		texts := make([]string, len(stale))
+		for j, i := range stale {
+			texts[j] = result[i].Name + ": " + result[i].Description
+		}
+		embeddings, err := provider.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("error embedding %d stale concepts: %w", len(stale), err)
+		}
+		for j, i := range stale {
+			result[i].Embedding = embeddings[j]
+		}
+	}
+
+	modelID := provider.ID()
+	if len(result) > 0 {
+		modelID = result[0].Embedding.ModelID
+	}
+	if err := SaveIndex(basePath, result, modelID); err != nil {
+		return nil, fmt.Errorf("error saving index: %w", err)
+	}
+
+	return result, nil
+}
+
+// IndexStats summarizes a persisted index's manifest against the current
+// concept definitions, for the `embeddings stats` command.
+type IndexStats struct {
+	ModelID   string
+	Dimension int
+	// Total is len(concepts).
+	Total int
+	// Cached is how many concepts' content hash still matches the manifest,
+	// i.e. would be reused rather than re-embedded by the next rebuild.
+	Cached int
+	// Stale is Total - Cached: new concepts plus ones whose description or
+	// synonyms changed since the manifest was written.
+	Stale int
+	// Orphaned is how many manifest entries have no matching concept
+	// anymore, left behind by a renamed or deleted concept. These are what
+	// `embeddings gc` removes.
+	Orphaned int
+}
+
+// HitRate is Cached/Total, the fraction of concepts a rebuild could skip
+// re-embedding entirely. Returns 0 for an empty concept set.
+func (s IndexStats) HitRate() float64 {
+	if s.Total == 0 {
+		return 0
+	}
+	return float64(s.Cached) / float64(s.Total)
+}
+
+// Stats loads the manifest at basePath and compares it against concepts,
+// without re-embedding or writing anything back.
+func Stats(basePath string, concepts []types.SecurityConcept) (IndexStats, error) {
+	manifest, err := os.ReadFile(manifestPath(basePath))
+	if err != nil {
+		return IndexStats{}, fmt.Errorf("error reading manifest: %w", err)
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(manifest, &m); err != nil {
+		return IndexStats{}, fmt.Errorf("error parsing manifest: %w", err)
+	}
+
+	priorHashes := make(map[string]string, len(m.Concepts))
+	for _, entry := range m.Concepts {
+		priorHashes[entry.Name] = entry.ContentHash
+	}
+
+	stats := IndexStats{ModelID: m.ModelID, Dimension: m.Dimension, Total: len(concepts)}
+
+	current := make(map[string]bool, len(concepts))
+	for _, concept := range concepts {
+		current[concept.Name] = true
+		if hash, ok := priorHashes[concept.Name]; ok && hash == conceptContentHash(concept) {
+			stats.Cached++
+		} else {
+			stats.Stale++
+		}
+	}
+
+	for name := range priorHashes {
+		if !current[name] {
+			stats.Orphaned++
+		}
+	}
+
+	return stats, nil
+}
+
+// GC rewrites the persisted index at basePath to drop manifest entries (and
+// their vectors) for concepts no longer present in concepts, returning how
+// many entries were removed. Concepts still present keep their stored
+// vector untouched - GC never re-embeds anything, it only reclaims space
+// from renamed or deleted concepts.
+func GC(basePath string, concepts []types.SecurityConcept) (int, error) {
+	manifest, vectors, err := LoadIndex(basePath)
+	if err != nil {
+		return 0, fmt.Errorf("error loading index: %w", err)
+	}
+
+	current := make(map[string]bool, len(concepts))
+	for _, concept := range concepts {
+		current[concept.Name] = true
+	}
+
+	kept := make([]types.SecurityConcept, 0, len(manifest.Concepts))
+	removed := 0
+	for _, entry := range manifest.Concepts {
+		if !current[entry.Name] {
+			removed++
+			continue
+		}
+		kept = append(kept, types.SecurityConcept{Name: entry.Name, Embedding: vectors[entry.Name]})
+	}
+
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if err := SaveIndex(basePath, kept, manifest.ModelID); err != nil {
+		return 0, fmt.Errorf("error saving compacted index: %w", err)
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,170 @@
+package embedding_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+func TestSaveLoadIndexRoundTrip(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Description: "is active", Embedding: types.Embedding{Vector: []float32{0.1, 0.2, 0.3}}},
+		{Name: "locked", Description: "reentrancy guard", Embedding: types.Embedding{Vector: []float32{0.4, 0.5, 0.6}}},
+	}
+
+	base := filepath.Join(t.TempDir(), "concepts")
+	if err := embedding.SaveIndex(base, concepts, "test-model"); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	manifest, vectors, err := embedding.LoadIndex(base)
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+
+	if manifest.ModelID != "test-model" || manifest.Dimension != 3 {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	for _, concept := range concepts {
+		got, ok := vectors[concept.Name]
+		if !ok {
+			t.Fatalf("missing vector for %q", concept.Name)
+		}
+		for i, v := range got.Vector {
+			if v != concept.Embedding.Vector[i] {
+				t.Errorf("concept %q: vector[%d] = %f, want %f", concept.Name, i, v, concept.Embedding.Vector[i])
+			}
+		}
+	}
+}
+
+func TestSyncConceptsReusesUnchangedVectors(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Description: "is active", Embedding: types.Embedding{Vector: []float32{1, 0}}},
+	}
+	base := filepath.Join(t.TempDir(), "concepts")
+
+	if err := embedding.SaveIndex(base, concepts, "counting"); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	calls := 0
+	provider := &countingProvider{calls: &calls}
+
+	updated, err := embedding.SyncConcepts(context.Background(), provider, concepts, base)
+	if err != nil {
+		t.Fatalf("SyncConcepts() error = %v", err)
+	}
+
+	if calls != 0 {
+		t.Errorf("expected no re-embedding for unchanged concepts, got %d provider calls", calls)
+	}
+	if updated[0].Embedding.Vector[0] != 1 {
+		t.Errorf("expected reused vector, got %+v", updated[0].Embedding.Vector)
+	}
+}
+
+func TestSyncConceptsRebuildsOnModelMismatch(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Description: "is active", Embedding: types.Embedding{Vector: []float32{1, 0}}},
+	}
+	base := filepath.Join(t.TempDir(), "concepts")
+
+	// Manifest was written by a different model than the one SyncConcepts is
+	// about to use: every stored vector belongs to a different embedding
+	// space and must be rejected rather than reused by content hash alone.
+	if err := embedding.SaveIndex(base, concepts, "some-other-model"); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	calls := 0
+	provider := &countingProvider{calls: &calls}
+
+	_, err := embedding.SyncConcepts(context.Background(), provider, concepts, base)
+	if err != nil {
+		t.Fatalf("SyncConcepts() error = %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("expected re-embedding on model mismatch, got %d provider calls", calls)
+	}
+}
+
+func TestStatsReportsCacheHitsAndOrphans(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Description: "is active", Embedding: types.Embedding{Vector: []float32{1, 0}}},
+		{Name: "locked", Description: "reentrancy guard", Embedding: types.Embedding{Vector: []float32{0, 1}}},
+	}
+	base := filepath.Join(t.TempDir(), "concepts")
+	if err := embedding.SaveIndex(base, concepts, "test-model"); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	// "active" is unchanged, "locked" was renamed to "guarded" (so it's a new
+	// concept, not a content-hash change of the old one).
+	current := []types.SecurityConcept{
+		concepts[0],
+		{Name: "guarded", Description: "reentrancy guard", Embedding: types.Embedding{Vector: []float32{0, 1}}},
+	}
+
+	stats, err := embedding.Stats(base, current)
+	if err != nil {
+		t.Fatalf("Stats() error = %v", err)
+	}
+	if stats.Total != 2 || stats.Cached != 1 || stats.Stale != 1 || stats.Orphaned != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if rate := stats.HitRate(); rate != 0.5 {
+		t.Errorf("HitRate() = %v, want 0.5", rate)
+	}
+}
+
+func TestGCRemovesOrphanedEntries(t *testing.T) {
+	concepts := []types.SecurityConcept{
+		{Name: "active", Description: "is active", Embedding: types.Embedding{Vector: []float32{1, 0}}},
+		{Name: "locked", Description: "reentrancy guard", Embedding: types.Embedding{Vector: []float32{0, 1}}},
+	}
+	base := filepath.Join(t.TempDir(), "concepts")
+	if err := embedding.SaveIndex(base, concepts, "test-model"); err != nil {
+		t.Fatalf("SaveIndex() error = %v", err)
+	}
+
+	removed, err := embedding.GC(base, concepts[:1])
+	if err != nil {
+		t.Fatalf("GC() error = %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("GC() removed = %d, want 1", removed)
+	}
+
+	manifest, vectors, err := embedding.LoadIndex(base)
+	if err != nil {
+		t.Fatalf("LoadIndex() error = %v", err)
+	}
+	if len(manifest.Concepts) != 1 {
+		t.Errorf("expected 1 remaining manifest entry, got %d", len(manifest.Concepts))
+	}
+	if _, ok := vectors["locked"]; ok {
+		t.Errorf("expected 'locked' vector to be gone after gc")
+	}
+}
+
+type countingProvider struct {
+	calls *int
+}
+
+func (p *countingProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	*p.calls++
+	vectors := make([]types.Embedding, len(texts))
+	for i := range texts {
+		vectors[i] = types.Embedding{Vector: []float32{1, 0}, ModelID: p.ID()}
+	}
+	return vectors, nil
+}
+
+func (p *countingProvider) Dimension() int { return 2 }
+func (p *countingProvider) ID() string     { return "counting" }
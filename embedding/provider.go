@@ -0,0 +1,414 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/jeffrydegrande/solidair/types"
+	openai "github.com/sashabaranov/go-openai"
+)
+
+// EmbeddingProvider is a remote embedding backend. Unlike LocalEmbedder it
+// can fail (network, auth, rate limit) and is expected to batch: one call
+// per N inputs rather than one HTTP round-trip per variable.
+type EmbeddingProvider interface {
+	// Embed computes vectors for a batch of texts, preserving input order.
+	Embed(ctx context.Context, texts []string) ([]types.Embedding, error)
+	// Dimension is the vector length this provider produces.
+	Dimension() int
+	// ID identifies the provider+model, stamped onto cached vectors so
+	// swapping providers invalidates stale entries instead of silently
+	// mixing vectors from different embedding spaces.
+	ID() string
+}
+
+// openAIDimensions maps each embedding model OpenAIClient might be pinned to
+// onto its vector length, since the API response doesn't carry it.
+var openAIDimensions = map[openai.EmbeddingModel]int{
+	openai.AdaEmbeddingV2:  1536,
+	openai.SmallEmbedding3: 1536,
+	openai.LargeEmbedding3: 3072,
+}
+
+// OpenAIProvider adapts OpenAIClient to the EmbeddingProvider interface.
+type OpenAIProvider struct {
+	Client *OpenAIClient
+}
+
+// NewOpenAIProvider wraps an existing OpenAIClient as a provider.
+func NewOpenAIProvider(client *OpenAIClient) *OpenAIProvider {
+	return &OpenAIProvider{Client: client}
+}
+
+func (p *OpenAIProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	return p.Client.GetEmbeddings(ctx, texts)
+}
+
+func (p *OpenAIProvider) Dimension() int {
+	if d, ok := openAIDimensions[p.Client.Model]; ok {
+		return d
+	}
+	return 1536
+}
+
+func (p *OpenAIProvider) ID() string { return "openai:" + string(p.Client.Model) }
+
+// httpJSONEmbedder holds the pieces shared by the HTTP-based providers below:
+// build a request body, POST it, and unmarshal vectors out of the response.
+type httpJSONEmbedder struct {
+	httpClient *http.Client
+}
+
+func (h httpJSONEmbedder) post(ctx context.Context, url string, headers map[string]string, body any, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("error encoding request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("error building request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	client := h.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response from %s: %w", url, err)
+	}
+
+	return nil
+}
+
+// CohereProvider embeds text via Cohere's /v1/embed endpoint.
+type CohereProvider struct {
+	httpJSONEmbedder
+	APIKey string
+	Model  string // e.g. "embed-english-v3.0"
+}
+
+// NewCohereProvider creates a provider for Cohere's embed API.
+func NewCohereProvider(apiKey, model string) *CohereProvider {
+	if model == "" {
+		model = "embed-english-v3.0"
+	}
+	return &CohereProvider{APIKey: apiKey, Model: model}
+}
+
+func (p *CohereProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	var out struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+
+	err := p.post(ctx, "https://api.cohere.ai/v1/embed",
+		map[string]string{"Authorization": "Bearer " + p.APIKey},
+		map[string]any{"texts": texts, "model": p.Model, "input_type": "search_document"},
+		&out,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(out.Embeddings))
+	}
+
+	result := make([]types.Embedding, len(out.Embeddings))
+	for i, v := range out.Embeddings {
+		result[i] = types.Embedding{Vector: v, ModelID: p.ID()}
+	}
+	return result, nil
+}
+
+func (p *CohereProvider) Dimension() int { return 1024 }
+
+func (p *CohereProvider) ID() string { return "cohere:" + p.Model }
+
+// VoyageProvider embeds text via Voyage AI's /v1/embeddings endpoint.
+type VoyageProvider struct {
+	httpJSONEmbedder
+	APIKey string
+	Model  string // e.g. "voyage-code-2"
+}
+
+// NewVoyageProvider creates a provider for Voyage AI's embed API.
+func NewVoyageProvider(apiKey, model string) *VoyageProvider {
+	if model == "" {
+		model = "voyage-code-2"
+	}
+	return &VoyageProvider{APIKey: apiKey, Model: model}
+}
+
+func (p *VoyageProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	var out struct {
+		Data []struct {
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+
+	err := p.post(ctx, "https://api.voyageai.com/v1/embeddings",
+		map[string]string{"Authorization": "Bearer " + p.APIKey},
+		map[string]any{"input": texts, "model": p.Model},
+		&out,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(out.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(out.Data))
+	}
+
+	result := make([]types.Embedding, len(out.Data))
+	for i, d := range out.Data {
+		result[i] = types.Embedding{Vector: d.Embedding, ModelID: p.ID()}
+	}
+	return result, nil
+}
+
+func (p *VoyageProvider) Dimension() int { return 1536 }
+
+func (p *VoyageProvider) ID() string { return "voyage:" + p.Model }
+
+// OllamaProvider embeds text via a local Ollama server's /api/embeddings
+// endpoint. Ollama's API only takes one prompt per request, so Embed issues
+// one call per text against the configured host.
+type OllamaProvider struct {
+	httpJSONEmbedder
+	Host  string // e.g. "http://localhost:11434"
+	Model string // e.g. "nomic-embed-text"
+}
+
+// NewOllamaProvider creates a provider for a local Ollama server.
+func NewOllamaProvider(host, model string) *OllamaProvider {
+	if host == "" {
+		host = "http://localhost:11434"
+	}
+	if model == "" {
+		model = "nomic-embed-text"
+	}
+	return &OllamaProvider{Host: host, Model: model}
+}
+
+func (p *OllamaProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	result := make([]types.Embedding, len(texts))
+
+	for i, text := range texts {
+		var out struct {
+			Embedding []float32 `json:"embedding"`
+		}
+
+		err := p.post(ctx, p.Host+"/api/embeddings", nil,
+			map[string]any{"model": p.Model, "prompt": text},
+			&out,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = types.Embedding{Vector: out.Embedding, ModelID: p.ID()}
+	}
+
+	return result, nil
+}
+
+func (p *OllamaProvider) Dimension() int { return 768 } // nomic-embed-text default
+
+func (p *OllamaProvider) ID() string { return "ollama:" + p.Model }
+
+// LlamaCppProvider embeds text via a llama.cpp server's /embedding endpoint
+// (llama.cpp --embedding serving a GGUF model). Like Ollama, it takes one
+// prompt per request, so Embed issues one call per text.
+type LlamaCppProvider struct {
+	httpJSONEmbedder
+	Host  string // e.g. "http://localhost:8080"
+	Model string // informational only; llama.cpp serves whatever model it was started with
+	Dims  int
+}
+
+// NewLlamaCppProvider creates a provider for a local llama.cpp server.
+// dimension must match the GGUF model's embedding size, since llama.cpp's
+// response doesn't identify it.
+func NewLlamaCppProvider(host, model string, dimension int) *LlamaCppProvider {
+	if host == "" {
+		host = "http://localhost:8080"
+	}
+	if dimension <= 0 {
+		dimension = 4096
+	}
+	return &LlamaCppProvider{Host: host, Model: model, Dims: dimension}
+}
+
+func (p *LlamaCppProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	result := make([]types.Embedding, len(texts))
+
+	for i, text := range texts {
+		var out struct {
+			Embedding []float32 `json:"embedding"`
+		}
+
+		err := p.post(ctx, p.Host+"/embedding", nil,
+			map[string]any{"content": text},
+			&out,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result[i] = types.Embedding{Vector: out.Embedding, ModelID: p.ID()}
+	}
+
+	return result, nil
+}
+
+func (p *LlamaCppProvider) Dimension() int { return p.Dims }
+
+func (p *LlamaCppProvider) ID() string { return "llamacpp:" + p.Model }
+
+// HuggingFaceProvider embeds text via the Hugging Face Inference API's
+// feature-extraction pipeline, for hosted sentence-transformer models.
+type HuggingFaceProvider struct {
+	httpJSONEmbedder
+	APIKey string
+	Model  string // e.g. "sentence-transformers/all-MiniLM-L6-v2"
+	Dims   int
+}
+
+// NewHuggingFaceProvider creates a provider for the Hugging Face Inference
+// API. dimension must match the model's output size, since the API response
+// doesn't identify it.
+func NewHuggingFaceProvider(apiKey, model string, dimension int) *HuggingFaceProvider {
+	if model == "" {
+		model = "sentence-transformers/all-MiniLM-L6-v2"
+	}
+	if dimension <= 0 {
+		dimension = 384
+	}
+	return &HuggingFaceProvider{APIKey: apiKey, Model: model, Dims: dimension}
+}
+
+func (p *HuggingFaceProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	var out [][]float32
+
+	url := "https://api-inference.huggingface.co/pipeline/feature-extraction/" + p.Model
+	err := p.post(ctx, url,
+		map[string]string{"Authorization": "Bearer " + p.APIKey},
+		map[string]any{"inputs": texts, "options": map[string]any{"wait_for_model": true}},
+		&out,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(out))
+	}
+
+	result := make([]types.Embedding, len(out))
+	for i, v := range out {
+		result[i] = types.Embedding{Vector: v, ModelID: p.ID()}
+	}
+	return result, nil
+}
+
+func (p *HuggingFaceProvider) Dimension() int { return p.Dims }
+
+func (p *HuggingFaceProvider) ID() string { return "huggingface:" + p.Model }
+
+// NewProvider builds an EmbeddingProvider by name, the factory behind the
+// --provider/--model/--host flags on matchCmd. host is only consulted by
+// the self-hosted backends (ollama, llamacpp); apiKey only by the hosted
+// ones (openai, huggingface, cohere, voyage).
+func NewProvider(name, model, host, apiKey string) (EmbeddingProvider, error) {
+	switch name {
+	case "", "openai":
+		if apiKey == "" {
+			return nil, fmt.Errorf("openai provider requires an API key (--api-key or OPENAI_API_KEY)")
+		}
+		return NewOpenAIProvider(NewOpenAIClientWithModel(apiKey, openai.EmbeddingModel(model))), nil
+	case "huggingface", "hf":
+		if apiKey == "" {
+			return nil, fmt.Errorf("huggingface provider requires an API key (--api-key)")
+		}
+		return NewHuggingFaceProvider(apiKey, model, 0), nil
+	case "cohere":
+		if apiKey == "" {
+			return nil, fmt.Errorf("cohere provider requires an API key (--api-key)")
+		}
+		return NewCohereProvider(apiKey, model), nil
+	case "voyage":
+		if apiKey == "" {
+			return nil, fmt.Errorf("voyage provider requires an API key (--api-key)")
+		}
+		return NewVoyageProvider(apiKey, model), nil
+	case "ollama":
+		return NewOllamaProvider(host, model), nil
+	case "llamacpp":
+		return NewLlamaCppProvider(host, model, 0), nil
+	case "local", "hash":
+		return NewLocalProvider(NewHashingEmbedder(0)), nil
+	case "gguf":
+		if model == "" {
+			return nil, fmt.Errorf("gguf provider requires --model to be a path to a .gguf file")
+		}
+		embedder, err := LoadGGUFEmbedder(model)
+		if err != nil {
+			return nil, fmt.Errorf("error loading GGUF model: %w", err)
+		}
+		return NewLocalProvider(embedder), nil
+	default:
+		return nil, fmt.Errorf("unknown embedding provider %q (want openai, huggingface, cohere, voyage, ollama, llamacpp, local, or gguf)", name)
+	}
+}
+
+// LocalProvider adapts a LocalEmbedder to the EmbeddingProvider interface, so
+// the local backends (the hashing embedder, or a GGUF model) can be selected
+// through the same --provider flag as the remote ones (--provider local,
+// hash, or gguf; no API key, no network).
+type LocalProvider struct {
+	Embedder LocalEmbedder
+}
+
+// NewLocalProvider wraps a LocalEmbedder as a provider.
+func NewLocalProvider(embedder LocalEmbedder) *LocalProvider {
+	return &LocalProvider{Embedder: embedder}
+}
+
+func (p *LocalProvider) Embed(ctx context.Context, texts []string) ([]types.Embedding, error) {
+	result := make([]types.Embedding, len(texts))
+	for i, text := range texts {
+		result[i] = p.Embedder.Embed(text)
+	}
+	return result, nil
+}
+
+func (p *LocalProvider) Dimension() int {
+	if h, ok := p.Embedder.(*HashingEmbedder); ok {
+		return h.Dimension
+	}
+	if h, ok := p.Embedder.(*TFIDFHashingEmbedder); ok {
+		return h.Dimension
+	}
+	if g, ok := p.Embedder.(*GGUFEmbedder); ok {
+		return g.Dimension()
+	}
+	return 0
+}
+
+func (p *LocalProvider) ID() string { return p.Embedder.ModelID() }
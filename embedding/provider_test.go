@@ -0,0 +1,66 @@
+package embedding_test
+
+import (
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+)
+
+func TestProviderIDsAreDistinct(t *testing.T) {
+	providers := []embedding.EmbeddingProvider{
+		embedding.NewOpenAIProvider(embedding.NewOpenAIClient("test-key")),
+		embedding.NewCohereProvider("test-key", ""),
+		embedding.NewVoyageProvider("test-key", ""),
+		embedding.NewOllamaProvider("", ""),
+		embedding.NewLlamaCppProvider("", "codellama", 4096),
+	}
+
+	seen := make(map[string]bool)
+	for _, p := range providers {
+		if p.ID() == "" {
+			t.Errorf("expected non-empty ID for %T", p)
+		}
+		if seen[p.ID()] {
+			t.Errorf("expected distinct provider IDs, got duplicate %q", p.ID())
+		}
+		seen[p.ID()] = true
+
+		if p.Dimension() <= 0 {
+			t.Errorf("expected positive dimension for %T, got %d", p, p.Dimension())
+		}
+	}
+}
+
+func TestNewProviderSelectsBackend(t *testing.T) {
+	cases := []struct {
+		name     string
+		provider string
+		apiKey   string
+		wantErr  bool
+	}{
+		{name: "openai with key", provider: "openai", apiKey: "test-key"},
+		{name: "openai without key", provider: "openai", wantErr: true},
+		{name: "cohere without key", provider: "cohere", wantErr: true},
+		{name: "ollama needs no key", provider: "ollama"},
+		{name: "llamacpp needs no key", provider: "llamacpp"},
+		{name: "unknown provider", provider: "bogus", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p, err := embedding.NewProvider(tc.provider, "", "", tc.apiKey)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got provider %T", p)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			if p.ID() == "" {
+				t.Errorf("expected non-empty ID")
+			}
+		})
+	}
+}
@@ -0,0 +1,207 @@
+package embedding
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jeffrydegrande/solidair/pkg/concepts"
+	"github.com/jeffrydegrande/solidair/types"
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteStore is a Store backed by a single SQLite file, replacing the
+// monolithic embeddings.toml with something that can be updated
+// incrementally instead of rewritten whole on every change.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS entries (
+	kind         TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	description  TEXT,
+	synonyms     TEXT,
+	source_file  TEXT NOT NULL DEFAULT '',
+	line_number  INTEGER NOT NULL DEFAULT 0,
+	content_hash TEXT,
+	model_id     TEXT,
+	vector       BLOB,
+	PRIMARY KEY (kind, name, source_file)
+);
+`
+
+// NewSQLiteStore opens (creating if necessary) a SQLite-backed Store at
+// path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening sqlite store: %w", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating schema: %w", err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLiteStore) Upsert(entry Entry) error {
+	_, err := s.db.Exec(
+		`INSERT INTO entries (kind, name, description, synonyms, source_file, line_number, content_hash, model_id, vector)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT (kind, name, source_file) DO UPDATE SET
+		   description = excluded.description,
+		   synonyms = excluded.synonyms,
+		   line_number = excluded.line_number,
+		   content_hash = excluded.content_hash,
+		   model_id = excluded.model_id,
+		   vector = excluded.vector`,
+		string(entry.Kind), entry.Name, entry.Description, strings.Join(entry.Synonyms, ","),
+		entry.SourceFile, entry.LineNumber, entry.ContentHash, entry.Embedding.ModelID,
+		encodeVector(entry.Embedding.Vector),
+	)
+	if err != nil {
+		return fmt.Errorf("error upserting %s %q: %w", entry.Kind, entry.Name, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) GetByConcept(name string) (Entry, bool, error) {
+	row := s.db.QueryRow(
+		`SELECT name, description, synonyms, content_hash, model_id, vector
+		 FROM entries WHERE kind = ? AND name = ? AND source_file = ''`,
+		string(ConceptEntry), name,
+	)
+
+	var entry Entry
+	var synonyms string
+	var vector []byte
+	entry.Kind = ConceptEntry
+	if err := row.Scan(&entry.Name, &entry.Description, &synonyms, &entry.ContentHash, &entry.Embedding.ModelID, &vector); err != nil {
+		if err == sql.ErrNoRows {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, fmt.Errorf("error reading concept %q: %w", name, err)
+	}
+
+	if synonyms != "" {
+		entry.Synonyms = strings.Split(synonyms, ",")
+	}
+	entry.Embedding.Vector = decodeVector(vector)
+	return entry, true, nil
+}
+
+// GetVariable returns the stored VariableEntry for name in sourceFile, if
+// any, so a caller can compare its ContentHash against the file's current
+// hash and skip re-embedding when nothing changed.
+func (s *SQLiteStore) GetVariable(name, sourceFile string) (Entry, bool, error) {
+	row := s.db.QueryRow(
+		`SELECT name, source_file, line_number, content_hash, model_id, vector
+		 FROM entries WHERE kind = ? AND name = ? AND source_file = ?`,
+		string(VariableEntry), name, sourceFile,
+	)
+
+	var entry Entry
+	var vector []byte
+	entry.Kind = VariableEntry
+	if err := row.Scan(&entry.Name, &entry.SourceFile, &entry.LineNumber, &entry.ContentHash, &entry.Embedding.ModelID, &vector); err != nil {
+		if err == sql.ErrNoRows {
+			return Entry{}, false, nil
+		}
+		return Entry{}, false, fmt.Errorf("error reading variable %q in %s: %w", name, sourceFile, err)
+	}
+
+	entry.Embedding.Vector = decodeVector(vector)
+	return entry, true, nil
+}
+
+func (s *SQLiteStore) NearestNeighbors(vec []float32, k int, filter func(Entry) bool) ([]Entry, error) {
+	rows, err := s.db.Query(
+		`SELECT kind, name, description, synonyms, source_file, line_number, content_hash, model_id, vector FROM entries`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error scanning entries: %w", err)
+	}
+	defer rows.Close()
+
+	queryEmbedding := types.Embedding{Vector: vec}
+
+	type scored struct {
+		entry Entry
+		score float32
+	}
+	var candidates []scored
+
+	for rows.Next() {
+		var entry Entry
+		var kind, synonyms string
+		var vector []byte
+		if err := rows.Scan(&kind, &entry.Name, &entry.Description, &synonyms, &entry.SourceFile,
+			&entry.LineNumber, &entry.ContentHash, &entry.Embedding.ModelID, &vector); err != nil {
+			return nil, fmt.Errorf("error reading entry: %w", err)
+		}
+		entry.Kind = EntryKind(kind)
+		if synonyms != "" {
+			entry.Synonyms = strings.Split(synonyms, ",")
+		}
+		entry.Embedding.Vector = decodeVector(vector)
+
+		if filter != nil && !filter(entry) {
+			continue
+		}
+		candidates = append(candidates, scored{entry: entry, score: CosineSimilarity(queryEmbedding, entry.Embedding)})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating entries: %w", err)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if k > len(candidates) {
+		k = len(candidates)
+	}
+
+	out := make([]Entry, k)
+	for i := 0; i < k; i++ {
+		out[i] = candidates[i].entry
+	}
+	return out, nil
+}
+
+// MigrateFromTOML imports concepts.toml/embeddings.toml (or either legacy
+// format concepts.LoadSecurityConcepts understands) into the store, but
+// only if it's still empty — this runs once, the first time a project
+// switches from the monolithic TOML file to a SQLite store.
+func (s *SQLiteStore) MigrateFromTOML() error {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM entries`).Scan(&count); err != nil {
+		return fmt.Errorf("error checking store for existing entries: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+
+	loaded, err := concepts.LoadSecurityConcepts()
+	if err != nil {
+		return fmt.Errorf("error loading legacy TOML concepts: %w", err)
+	}
+
+	for _, concept := range loaded {
+		err := s.Upsert(Entry{
+			Kind:        ConceptEntry,
+			Name:        concept.Name,
+			Description: concept.Description,
+			Synonyms:    concept.Synonyms,
+			Embedding:   concept.Embedding,
+		})
+		if err != nil {
+			return fmt.Errorf("error migrating concept %q: %w", concept.Name, err)
+		}
+	}
+	return nil
+}
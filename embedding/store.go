@@ -0,0 +1,77 @@
+package embedding
+
+import (
+	"encoding/binary"
+	"math"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// EntryKind distinguishes a Store entry for a security concept from one for
+// an extracted variable, since both share the same embedding + metadata
+// shape but are looked up differently (GetByConcept only wants the former).
+type EntryKind string
+
+const (
+	ConceptEntry  EntryKind = "concept"
+	VariableEntry EntryKind = "variable"
+)
+
+// Entry is one row of a Store: an embedded concept or variable, plus enough
+// metadata to invalidate and re-embed it without a full table scan.
+type Entry struct {
+	Kind EntryKind
+	Name string // concept name, or variable name
+
+	// Concept-only fields, empty for a VariableEntry.
+	Description string
+	Synonyms    []string
+
+	// Variable-only fields, empty for a ConceptEntry.
+	SourceFile string
+	LineNumber uint32
+
+	// ContentHash is the hash of whatever produced this entry's embedding
+	// (a concept's description+synonyms, or a variable's source file) so
+	// callers can skip re-embedding when it's unchanged.
+	ContentHash string
+
+	Embedding types.Embedding
+}
+
+// Store persists concepts and variables alongside their embeddings so large
+// projects don't need to reload and re-embed everything from a monolithic
+// embeddings.toml on every run.
+type Store interface {
+	// Upsert inserts entry, or replaces the existing row with the same
+	// Kind, Name, and SourceFile.
+	Upsert(entry Entry) error
+	// GetByConcept returns the stored ConceptEntry with the given name, if
+	// any.
+	GetByConcept(name string) (Entry, bool, error)
+	// NearestNeighbors returns the k entries passing filter (nil accepts
+	// everything) with the highest cosine similarity to vec, best first.
+	// The initial implementation is a brute-force scan; Store's interface
+	// leaves room for a backing ANN index later without callers changing.
+	NearestNeighbors(vec []float32, k int, filter func(Entry) bool) ([]Entry, error)
+	Close() error
+}
+
+// encodeVector packs a []float32 into little-endian bytes for storage as a
+// BLOB.
+func encodeVector(vec []float32) []byte {
+	buf := make([]byte, 4*len(vec))
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+// decodeVector unpacks encodeVector's output back into a []float32.
+func decodeVector(buf []byte) []float32 {
+	vec := make([]float32, len(buf)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vec
+}
@@ -0,0 +1,149 @@
+package embedding_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+func newTestStore(t *testing.T) *embedding.SQLiteStore {
+	t.Helper()
+	store, err := embedding.NewSQLiteStore(filepath.Join(t.TempDir(), "store.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteStore() error = %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestSQLiteStoreUpsertAndGetByConcept(t *testing.T) {
+	store := newTestStore(t)
+
+	entry := embedding.Entry{
+		Kind:        embedding.ConceptEntry,
+		Name:        "locked",
+		Description: "reentrancy guard",
+		Synonyms:    []string{"mutex", "guard"},
+		ContentHash: "abc123",
+		Embedding:   types.Embedding{Vector: []float32{0.1, 0.2, 0.3}, ModelID: "test-model"},
+	}
+	if err := store.Upsert(entry); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	got, ok, err := store.GetByConcept("locked")
+	if err != nil {
+		t.Fatalf("GetByConcept() error = %v", err)
+	}
+	if !ok {
+		t.Fatal("GetByConcept() found = false, want true")
+	}
+	if got.Description != entry.Description || got.ContentHash != entry.ContentHash {
+		t.Errorf("GetByConcept() = %+v, want description/hash matching %+v", got, entry)
+	}
+	if len(got.Synonyms) != 2 || got.Synonyms[0] != "mutex" {
+		t.Errorf("GetByConcept() synonyms = %v, want [mutex guard]", got.Synonyms)
+	}
+	for i, v := range got.Embedding.Vector {
+		if v != entry.Embedding.Vector[i] {
+			t.Errorf("GetByConcept() vector[%d] = %f, want %f", i, v, entry.Embedding.Vector[i])
+		}
+	}
+
+	// Upserting again with the same key replaces, rather than duplicates.
+	entry.ContentHash = "def456"
+	if err := store.Upsert(entry); err != nil {
+		t.Fatalf("second Upsert() error = %v", err)
+	}
+	got, _, err = store.GetByConcept("locked")
+	if err != nil {
+		t.Fatalf("GetByConcept() error = %v", err)
+	}
+	if got.ContentHash != "def456" {
+		t.Errorf("GetByConcept() after update ContentHash = %q, want %q", got.ContentHash, "def456")
+	}
+}
+
+func TestSQLiteStoreGetByConceptMissing(t *testing.T) {
+	store := newTestStore(t)
+
+	_, ok, err := store.GetByConcept("nonexistent")
+	if err != nil {
+		t.Fatalf("GetByConcept() error = %v", err)
+	}
+	if ok {
+		t.Error("GetByConcept() found = true, want false")
+	}
+}
+
+func TestSQLiteStoreNearestNeighbors(t *testing.T) {
+	store := newTestStore(t)
+
+	entries := []embedding.Entry{
+		{Kind: embedding.ConceptEntry, Name: "active", Embedding: types.Embedding{Vector: []float32{1, 0, 0}}},
+		{Kind: embedding.ConceptEntry, Name: "locked", Embedding: types.Embedding{Vector: []float32{0, 1, 0}}},
+		{Kind: embedding.VariableEntry, Name: "is_active", SourceFile: "a.cairo", Embedding: types.Embedding{Vector: []float32{0.9, 0.1, 0}}},
+	}
+	for _, e := range entries {
+		if err := store.Upsert(e); err != nil {
+			t.Fatalf("Upsert(%q) error = %v", e.Name, err)
+		}
+	}
+
+	results, err := store.NearestNeighbors([]float32{1, 0, 0}, 2, nil)
+	if err != nil {
+		t.Fatalf("NearestNeighbors() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("NearestNeighbors() returned %d results, want 2", len(results))
+	}
+	if results[0].Name != "active" {
+		t.Errorf("NearestNeighbors()[0].Name = %q, want %q", results[0].Name, "active")
+	}
+
+	conceptsOnly, err := store.NearestNeighbors([]float32{1, 0, 0}, 10, func(e embedding.Entry) bool {
+		return e.Kind == embedding.ConceptEntry
+	})
+	if err != nil {
+		t.Fatalf("NearestNeighbors() with filter error = %v", err)
+	}
+	if len(conceptsOnly) != 2 {
+		t.Errorf("NearestNeighbors() with concept filter returned %d results, want 2", len(conceptsOnly))
+	}
+}
+
+func TestSQLiteStoreGetVariable(t *testing.T) {
+	store := newTestStore(t)
+
+	if err := store.Upsert(embedding.Entry{
+		Kind:        embedding.VariableEntry,
+		Name:        "balance",
+		SourceFile:  "vault.cairo",
+		LineNumber:  12,
+		ContentHash: "filehash1",
+		Embedding:   types.Embedding{Vector: []float32{0.5, 0.5}},
+	}); err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	got, ok, err := store.GetVariable("balance", "vault.cairo")
+	if err != nil {
+		t.Fatalf("GetVariable() error = %v", err)
+	}
+	if !ok {
+		t.Fatal("GetVariable() found = false, want true")
+	}
+	if got.ContentHash != "filehash1" || got.LineNumber != 12 {
+		t.Errorf("GetVariable() = %+v, want matching ContentHash/LineNumber", got)
+	}
+
+	_, ok, err = store.GetVariable("balance", "other.cairo")
+	if err != nil {
+		t.Fatalf("GetVariable() error = %v", err)
+	}
+	if ok {
+		t.Error("GetVariable() for different source file found = true, want false")
+	}
+}
@@ -0,0 +1,28 @@
+package languages
+
+import (
+	"unsafe"
+
+	"github.com/jeffrydegrande/solidair/cairo"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// cairoBackend is the Backend for Cairo source (.cairo), wrapping the
+// existing cairo package's grammar binding.
+type cairoBackend struct{}
+
+func (cairoBackend) Name() string { return "cairo" }
+
+func (cairoBackend) Extensions() []string { return []string{".cairo"} }
+
+func (cairoBackend) TSLanguage() *tree_sitter.Language {
+	return tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))
+}
+
+func (cairoBackend) Parse(source []byte) (*tree_sitter.Tree, error) {
+	return cairo.Parse(source)
+}
+
+func init() {
+	Register(cairoBackend{})
+}
@@ -0,0 +1,69 @@
+// Package languages defines the Backend interface solidair's query engine
+// parses source through, and a registry that resolves one by file
+// extension. RunQueries and ReadQueryFiles used to hardcode Cairo's
+// tree-sitter grammar; going through a Backend instead lets a mixed-language
+// repo (Cairo and Solidity, say) get scanned in a single pass, with each
+// file routed to the grammar and query subdirectory that actually matches
+// it.
+package languages
+
+import (
+	"path/filepath"
+
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// Backend adapts one Tree-sitter grammar to solidair's query engine.
+type Backend interface {
+	// Name identifies the backend for its queries/<Name> subdirectory and
+	// anywhere else a result needs to say which language it came from.
+	Name() string
+	// Extensions lists the file extensions (with leading ".") this backend
+	// parses, used to route a file to the right backend by its suffix.
+	Extensions() []string
+	// TSLanguage returns the compiled Tree-sitter grammar queries are
+	// compiled against.
+	TSLanguage() *tree_sitter.Language
+	// Parse parses source into a fresh Tree-sitter tree.
+	Parse(source []byte) (*tree_sitter.Tree, error)
+}
+
+// registry maps a file extension (e.g. ".cairo") to the Backend that handles
+// it, populated by each backend package's own init() calling Register.
+var registry = map[string]Backend{}
+
+// Register adds backend to the registry under every extension it reports
+// via Extensions. A later Register for the same extension replaces the
+// earlier one.
+func Register(backend Backend) {
+	for _, ext := range backend.Extensions() {
+		registry[ext] = backend
+	}
+}
+
+// ForExtension returns the backend registered for ext (e.g. ".cairo"), or
+// false if none matches.
+func ForExtension(ext string) (Backend, bool) {
+	backend, ok := registry[ext]
+	return backend, ok
+}
+
+// ForPath returns the backend that handles path's extension, or false if no
+// backend is registered for it.
+func ForPath(path string) (Backend, bool) {
+	return ForExtension(filepath.Ext(path))
+}
+
+// Names returns every registered backend's Name, deduplicated, for building
+// queries/<name> subdirectory paths.
+func Names() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, backend := range registry {
+		if !seen[backend.Name()] {
+			seen[backend.Name()] = true
+			names = append(names, backend.Name())
+		}
+	}
+	return names
+}
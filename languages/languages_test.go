@@ -0,0 +1,61 @@
+package languages_test
+
+import (
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/languages"
+)
+
+func TestForExtensionResolvesRegisteredBackends(t *testing.T) {
+	backend, ok := languages.ForExtension(".cairo")
+	if !ok {
+		t.Fatalf("expected a backend registered for .cairo")
+	}
+	if backend.Name() != "cairo" {
+		t.Errorf("Name() = %q, want %q", backend.Name(), "cairo")
+	}
+
+	backend, ok = languages.ForExtension(".sol")
+	if !ok {
+		t.Fatalf("expected a backend registered for .sol")
+	}
+	if backend.Name() != "solidity" {
+		t.Errorf("Name() = %q, want %q", backend.Name(), "solidity")
+	}
+}
+
+func TestForExtensionUnknown(t *testing.T) {
+	if _, ok := languages.ForExtension(".rs"); ok {
+		t.Errorf("expected no backend registered for .rs")
+	}
+}
+
+func TestForPathUsesFileExtension(t *testing.T) {
+	backend, ok := languages.ForPath("src/token.cairo")
+	if !ok || backend.Name() != "cairo" {
+		t.Errorf("ForPath(%q) = %v, %v, want cairo backend", "src/token.cairo", backend, ok)
+	}
+
+	if _, ok := languages.ForPath("README.md"); ok {
+		t.Errorf("expected no backend registered for README.md")
+	}
+}
+
+func TestNamesListsEveryRegisteredBackendOnce(t *testing.T) {
+	names := languages.Names()
+	seen := make(map[string]int)
+	for _, name := range names {
+		seen[name]++
+	}
+	for name, count := range seen {
+		if count > 1 {
+			t.Errorf("Names() listed %q %d times, want once", name, count)
+		}
+	}
+	if seen["cairo"] == 0 {
+		t.Errorf("expected Names() to include \"cairo\"")
+	}
+	if seen["solidity"] == 0 {
+		t.Errorf("expected Names() to include \"solidity\"")
+	}
+}
@@ -0,0 +1,28 @@
+package languages
+
+import (
+	"unsafe"
+
+	"github.com/jeffrydegrande/solidair/solidity"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// solidityBackend is the Backend for Solidity source (.sol), wrapping the
+// solidity package's grammar binding.
+type solidityBackend struct{}
+
+func (solidityBackend) Name() string { return "solidity" }
+
+func (solidityBackend) Extensions() []string { return []string{".sol"} }
+
+func (solidityBackend) TSLanguage() *tree_sitter.Language {
+	return tree_sitter.NewLanguage(unsafe.Pointer(solidity.Language()))
+}
+
+func (solidityBackend) Parse(source []byte) (*tree_sitter.Tree, error) {
+	return solidity.Parse(source)
+}
+
+func init() {
+	Register(solidityBackend{})
+}
@@ -0,0 +1,91 @@
+// Package baseline implements a gitleaks-style allowlist of known findings,
+// so solidair can be adopted on an existing Cairo codebase without every
+// analyze run drowning in pre-existing results. A baseline file records each
+// finding's Fingerprint; analyze drops any result whose fingerprint is
+// already in the baseline, and --update-baseline rewrites it from the
+// current scan.
+package baseline
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// DefaultFilename is the baseline file solidair looks for in a project root
+// when --baseline is given without rewriting the default.
+const DefaultFilename = ".solidair-baseline.json"
+
+// Entry is one baselined finding, kept around in the file (rather than just
+// its fingerprint) so a human can tell what a hash refers to.
+type Entry struct {
+	QueryName   string `json:"query_name"`
+	FilePath    string `json:"file_path"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// Baseline is the set of fingerprints a project has already accepted,
+// loaded from a baseline file.
+type Baseline struct {
+	fingerprints map[string]bool
+}
+
+// Load reads the baseline file at path. A missing file isn't an error: it
+// returns an empty Baseline that matches nothing, so a project's first
+// --baseline run behaves like it had none yet.
+func Load(path string) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Baseline{}, nil
+		}
+		return nil, fmt.Errorf("error reading baseline %s: %w", path, err)
+	}
+
+	var entries []Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %w", path, err)
+	}
+
+	fingerprints := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		fingerprints[e.Fingerprint] = true
+	}
+	return &Baseline{fingerprints: fingerprints}, nil
+}
+
+// Contains reports whether fingerprint was already accepted into the
+// baseline. A nil Baseline matches nothing.
+func (b *Baseline) Contains(fingerprint string) bool {
+	if b == nil {
+		return false
+	}
+	return b.fingerprints[fingerprint]
+}
+
+// Save writes entries to path as indented JSON, replacing whatever baseline
+// was there before.
+func Save(path string, entries []Entry) error {
+	data, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding baseline: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing baseline %s: %w", path, err)
+	}
+	return nil
+}
+
+// Fingerprint derives a stable identity for a finding from its query name,
+// file path, normalized code span, and enclosingConstruct - the name of the
+// function or struct the match is nested in, rather than its line number -
+// so a trivial edit elsewhere in the file (adding a line above the match)
+// doesn't invalidate an already-baselined finding.
+func Fingerprint(queryName, filePath, code, enclosingConstruct string) string {
+	normalized := strings.Join(strings.Fields(code), " ")
+	sum := sha256.Sum256([]byte(queryName + "|" + filePath + "|" + enclosingConstruct + "|" + normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,57 @@
+package baseline_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/pkg/baseline"
+)
+
+func TestLoadMissingFileReturnsEmptyBaseline(t *testing.T) {
+	b, err := baseline.Load(filepath.Join(t.TempDir(), "nonexistent.json"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if b.Contains(baseline.Fingerprint("reentrancy", "src/a.cairo", "call()", "function_item:withdraw")) {
+		t.Errorf("expected empty Baseline to contain nothing")
+	}
+}
+
+func TestSaveAndLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), baseline.DefaultFilename)
+	fp := baseline.Fingerprint("reentrancy", "src/a.cairo", "call()", "function_item:withdraw")
+
+	err := baseline.Save(path, []baseline.Entry{
+		{QueryName: "reentrancy", FilePath: "src/a.cairo", Fingerprint: fp},
+	})
+	if err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	b, err := baseline.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !b.Contains(fp) {
+		t.Errorf("expected baseline to contain saved fingerprint")
+	}
+	if b.Contains(baseline.Fingerprint("reentrancy", "src/a.cairo", "other()", "function_item:withdraw")) {
+		t.Errorf("expected unrelated fingerprint not to be in baseline")
+	}
+}
+
+func TestFingerprintStableAcrossWhitespaceChanges(t *testing.T) {
+	a := baseline.Fingerprint("reentrancy", "src/a.cairo", "call( );", "function_item:withdraw")
+	b := baseline.Fingerprint("reentrancy", "src/a.cairo", "call(  );", "function_item:withdraw")
+	if a != b {
+		t.Errorf("expected whitespace-normalized code to produce the same fingerprint")
+	}
+}
+
+func TestFingerprintChangesWithEnclosingConstruct(t *testing.T) {
+	a := baseline.Fingerprint("reentrancy", "src/a.cairo", "call();", "function_item:withdraw")
+	b := baseline.Fingerprint("reentrancy", "src/a.cairo", "call();", "function_item:deposit")
+	if a == b {
+		t.Errorf("expected different enclosing constructs to produce different fingerprints")
+	}
+}
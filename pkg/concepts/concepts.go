@@ -5,7 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/jeffrydegrande/solidair/pkg/types"
+	"github.com/jeffrydegrande/solidair/types"
 	"github.com/pelletier/go-toml/v2"
 )
 
@@ -92,4 +92,4 @@ func SaveConceptsFile(concepts []types.SecurityConcept, outputDir string) error
 	}
 
 	return nil
-}
\ No newline at end of file
+}
@@ -5,7 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
-	"github.com/jeffrydegrande/solidair/pkg/types"
+	"github.com/jeffrydegrande/solidair/types"
 	"github.com/pelletier/go-toml/v2"
 )
 
@@ -15,13 +15,13 @@ func LoadSecurityConcepts() ([]types.SecurityConcept, error) {
 	embedDirs := []string{
 		"embeddings", // Current directory
 	}
-	
+
 	// Add executable directory if possible
 	if execPath, err := os.Executable(); err == nil {
 		execDir := filepath.Dir(execPath)
 		embedDirs = append(embedDirs, filepath.Join(execDir, "embeddings"))
 	}
-	
+
 	// Find the first existing embeddings directory
 	var embeddingsDir string
 	for _, dir := range embedDirs {
@@ -30,17 +30,17 @@ func LoadSecurityConcepts() ([]types.SecurityConcept, error) {
 			break
 		}
 	}
-	
+
 	if embeddingsDir == "" {
 		// Fallback to default concepts without embeddings
 		fmt.Println("Warning: No embeddings directory found. Using default concepts without embeddings.")
 		return DefaultSecurityConcepts(), nil
 	}
-	
+
 	// Try to load according to the new format (separate concepts and embeddings files)
 	conceptsFile := filepath.Join(embeddingsDir, "concepts.toml")
 	embeddingsFile := filepath.Join(embeddingsDir, "embeddings.toml")
-	
+
 	var conceptsExist, embeddingsExist bool
 	if _, err := os.Stat(conceptsFile); err == nil {
 		conceptsExist = true
@@ -48,7 +48,7 @@ func LoadSecurityConcepts() ([]types.SecurityConcept, error) {
 	if _, err := os.Stat(embeddingsFile); err == nil {
 		embeddingsExist = true
 	}
-	
+
 	// Check for legacy format if one of the new files is missing
 	if !conceptsExist || !embeddingsExist {
 		// Try legacy format with a single file
@@ -57,64 +57,84 @@ func LoadSecurityConcepts() ([]types.SecurityConcept, error) {
 			fmt.Println("Using legacy format security concepts file.")
 			return loadLegacySecurityConcepts(legacyFile)
 		}
-		
+
 		// Also try legacy format with concepts_metadata.toml
 		legacyMetadataFile := filepath.Join(embeddingsDir, "concepts_metadata.toml")
 		if _, err := os.Stat(legacyMetadataFile); err == nil {
 			fmt.Println("Using legacy format with concepts_metadata.toml.")
 			return loadLegacyMetadataFormat(embeddingsDir)
 		}
-		
+
 		// Fallback to default concepts without embeddings
 		fmt.Println("Warning: Missing concept or embedding files. Using default concepts without embeddings.")
 		return DefaultSecurityConcepts(), nil
 	}
-	
+
 	// Load concepts file
 	conceptsData, err := os.ReadFile(conceptsFile)
 	if err != nil {
 		return nil, fmt.Errorf("error reading concepts file: %w", err)
 	}
-	
+
 	var conceptsConfig struct {
 		Concepts []types.SecurityConcept `toml:"concepts"`
 	}
-	
+
 	if err := toml.Unmarshal(conceptsData, &conceptsConfig); err != nil {
 		return nil, fmt.Errorf("error parsing concepts file: %w", err)
 	}
-	
+
 	// Create a map for easier lookup
 	conceptsMap := make(map[string]*types.SecurityConcept)
 	for i := range conceptsConfig.Concepts {
 		conceptsMap[conceptsConfig.Concepts[i].Name] = &conceptsConfig.Concepts[i]
 	}
-	
+
 	// Load embeddings file
 	embeddingsData, err := os.ReadFile(embeddingsFile)
 	if err != nil {
 		fmt.Printf("Warning: Error reading embeddings file: %v. Using concepts without embeddings.\n", err)
 		return conceptsConfig.Concepts, nil
 	}
-	
+
 	var embeddingsConfig struct {
 		Embeddings []types.EmbeddingEntry `toml:"embeddings"`
 	}
-	
+
 	if err := toml.Unmarshal(embeddingsData, &embeddingsConfig); err != nil {
 		fmt.Printf("Warning: Error parsing embeddings file: %v. Using concepts without embeddings.\n", err)
 		return conceptsConfig.Concepts, nil
 	}
-	
-	// Merge embeddings into concepts
+
+	// Merge embeddings into concepts. embeddings.toml may hold more than one
+	// embedder's vector per concept (one EmbeddingEntry each, sharing
+	// ConceptName); all of them land in Embeddings, and whichever matches
+	// PreferredEmbedder - or the first one seen, if that's unset - becomes
+	// the concept's default Embedding.
 	for _, entry := range embeddingsConfig.Embeddings {
-		if concept, exists := conceptsMap[entry.ConceptName]; exists {
-			concept.Embedding = entry.Embedding
-		} else {
+		concept, exists := conceptsMap[entry.ConceptName]
+		if !exists {
 			fmt.Printf("Warning: Found embedding for unknown concept '%s'\n", entry.ConceptName)
+			continue
+		}
+
+		key := entry.EmbedderName
+		if key == "" {
+			key = entry.Embedding.ModelID
+		}
+		if concept.Embeddings == nil {
+			concept.Embeddings = make(map[string]types.Embedding)
+		}
+		concept.Embeddings[key] = entry.Embedding
+
+		switch {
+		case concept.PreferredEmbedder != "" && key == concept.PreferredEmbedder:
+			concept.Embedding = entry.Embedding
+		case concept.PreferredEmbedder == "" && concept.Embedding.Vector == nil:
+			concept.Embedding = entry.Embedding
 		}
 	}
-	
+
 	return conceptsConfig.Concepts, nil
 }
 
@@ -145,47 +165,47 @@ func loadLegacyMetadataFormat(embeddingsDir string) ([]types.SecurityConcept, er
 	if err != nil {
 		return nil, fmt.Errorf("error reading concept metadata: %w", err)
 	}
-	
+
 	var config struct {
 		Concepts []types.SecurityConcept `toml:"concepts"`
 	}
-	
+
 	if err := toml.Unmarshal(metadataData, &config); err != nil {
 		return nil, fmt.Errorf("error parsing concept metadata: %w", err)
 	}
-	
+
 	// Now load each embedding file from the legacy format
 	embeddingsSubdir := filepath.Join(embeddingsDir, "embeddings")
 	if _, err := os.Stat(embeddingsSubdir); err == nil {
 		for i, concept := range config.Concepts {
 			embeddingFile := filepath.Join(embeddingsSubdir, fmt.Sprintf("%s.toml", concept.Name))
-			
+
 			// Skip if embedding file doesn't exist
 			if _, err := os.Stat(embeddingFile); os.IsNotExist(err) {
 				fmt.Printf("Warning: No embedding file found for concept '%s'\n", concept.Name)
 				continue
 			}
-			
+
 			// Read the embedding file
 			embeddingData, err := os.ReadFile(embeddingFile)
 			if err != nil {
 				fmt.Printf("Warning: Error reading embedding for '%s': %v\n", concept.Name, err)
 				continue
 			}
-			
+
 			var embeddingConfig struct {
 				Embedding types.Embedding `toml:"embedding"`
 			}
-			
+
 			if err := toml.Unmarshal(embeddingData, &embeddingConfig); err != nil {
 				fmt.Printf("Warning: Error parsing embedding for '%s': %v\n", concept.Name, err)
 				continue
 			}
-			
+
 			// Add the embedding to the concept
 			config.Concepts[i].Embedding = embeddingConfig.Embedding
 		}
 	}
-	
+
 	return config.Concepts, nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,148 @@
+package concepts
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeffrydegrande/solidair/types"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// ConceptPack is a third-party *.toml file contributing concepts (and,
+// optionally, query templates for them) without patching the binary - e.g.
+// a DeFi, bridge, or governance-specific pack.
+type ConceptPack struct {
+	Name    string `toml:"pack_name"`
+	Version string `toml:"pack_version"`
+
+	Concepts []types.SecurityConcept `toml:"concepts"`
+
+	// Templates holds raw query-template source alongside the concepts that
+	// use it, one string per template, in the same `${...}` DSL cmd.ParseQueryTemplate
+	// parses. Kept as text here since template parsing lives in cmd, a layer
+	// above pkg/concepts.
+	Templates []string `toml:"templates,omitempty"`
+}
+
+// conceptsPathEnv and xdgConfigEnv are the environment variables consulted
+// by SearchPaths, alongside the --concepts-dir flag.
+const (
+	conceptsPathEnv = "SOLIDAIR_CONCEPTS_PATH"
+	xdgConfigEnv    = "XDG_CONFIG_HOME"
+)
+
+// SearchPaths returns the directories LoadConceptPacks should scan for
+// *.toml pack files, in priority order: the --concepts-dir flag (if set),
+// every entry of $SOLIDAIR_CONCEPTS_PATH (colon-separated, like $PATH), and
+// finally $XDG_CONFIG_HOME/solidair/concepts.d (or ~/.config/solidair/concepts.d
+// when XDG_CONFIG_HOME is unset). Nonexistent directories are kept; callers
+// skip them when scanning rather than erroring here.
+func SearchPaths(conceptsDirFlag string) []string {
+	var paths []string
+
+	if conceptsDirFlag != "" {
+		paths = append(paths, conceptsDirFlag)
+	}
+
+	if env := os.Getenv(conceptsPathEnv); env != "" {
+		paths = append(paths, strings.Split(env, ":")...)
+	}
+
+	configHome := os.Getenv(xdgConfigEnv)
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "solidair", "concepts.d"))
+	}
+
+	return paths
+}
+
+// LoadConceptPacks reads every *.toml file in each of searchPaths as a
+// ConceptPack, stamping each concept's Pack field with the pack's name.
+// Directories that don't exist are skipped rather than erroring, since most
+// of SearchPaths' defaults won't exist for a user who hasn't set up any
+// packs. A pack file that fails to parse is an error, since unlike a
+// missing directory it signals a pack the user meant to load is broken.
+func LoadConceptPacks(searchPaths []string) ([]types.SecurityConcept, []string, error) {
+	var concepts []types.SecurityConcept
+	var templates []string
+
+	for _, dir := range searchPaths {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, nil, fmt.Errorf("error reading concepts dir %q: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() || filepath.Ext(entry.Name()) != ".toml" {
+				continue
+			}
+
+			path := filepath.Join(dir, entry.Name())
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading concept pack %q: %w", path, err)
+			}
+
+			var pack ConceptPack
+			if err := toml.Unmarshal(data, &pack); err != nil {
+				return nil, nil, fmt.Errorf("error parsing concept pack %q: %w", path, err)
+			}
+
+			for _, c := range pack.Concepts {
+				c.Pack = pack.Name
+				concepts = append(concepts, c)
+			}
+			templates = append(templates, pack.Templates...)
+		}
+	}
+
+	return concepts, templates, nil
+}
+
+// LoadAllSecurityConcepts loads the builtin concepts (plus any precomputed
+// embeddings, via LoadSecurityConcepts) and merges in every concept pack
+// found under SearchPaths(conceptsDirFlag). A pack concept with the same
+// Name as a builtin one replaces it, so a pack can override a builtin
+// concept's description or synonyms.
+func LoadAllSecurityConcepts(conceptsDirFlag string) ([]types.SecurityConcept, error) {
+	builtin, err := LoadSecurityConcepts()
+	if err != nil {
+		return nil, err
+	}
+
+	packed, _, err := LoadConceptPacks(SearchPaths(conceptsDirFlag))
+	if err != nil {
+		return nil, err
+	}
+	if len(packed) == 0 {
+		return builtin, nil
+	}
+
+	merged := make([]types.SecurityConcept, 0, len(builtin)+len(packed))
+	index := make(map[string]int, len(builtin))
+	for _, c := range builtin {
+		index[c.Name] = len(merged)
+		merged = append(merged, c)
+	}
+
+	for _, c := range packed {
+		if i, ok := index[c.Name]; ok {
+			merged[i] = c
+			continue
+		}
+		index[c.Name] = len(merged)
+		merged = append(merged, c)
+	}
+
+	return merged, nil
+}
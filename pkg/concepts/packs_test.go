@@ -0,0 +1,86 @@
+package concepts_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/pkg/concepts"
+)
+
+func writePack(t *testing.T, dir, name, toml string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(toml), 0644); err != nil {
+		t.Fatalf("writing pack file: %v", err)
+	}
+}
+
+func TestLoadConceptPacksTagsPackName(t *testing.T) {
+	dir := t.TempDir()
+	writePack(t, dir, "defi.toml", `
+pack_name = "defi-pack"
+pack_version = "1.0.0"
+
+[[concepts]]
+name = "flash_loan"
+description = "flash loan callback"
+synonyms = ["flashloan"]
+severity = "high"
+cwe = "CWE-841"
+`)
+
+	loaded, _, err := concepts.LoadConceptPacks([]string{dir})
+	if err != nil {
+		t.Fatalf("LoadConceptPacks() error = %v", err)
+	}
+
+	if len(loaded) != 1 {
+		t.Fatalf("expected 1 concept, got %d", len(loaded))
+	}
+	if loaded[0].Pack != "defi-pack" {
+		t.Errorf("Pack = %q, want %q", loaded[0].Pack, "defi-pack")
+	}
+	if loaded[0].Severity != "high" || loaded[0].CWE != "CWE-841" {
+		t.Errorf("unexpected metadata: %+v", loaded[0])
+	}
+}
+
+func TestLoadConceptPacksSkipsMissingDir(t *testing.T) {
+	loaded, templates, err := concepts.LoadConceptPacks([]string{filepath.Join(t.TempDir(), "nonexistent")})
+	if err != nil {
+		t.Fatalf("LoadConceptPacks() error = %v", err)
+	}
+	if len(loaded) != 0 || len(templates) != 0 {
+		t.Errorf("expected nothing loaded from a missing directory, got %d concepts, %d templates", len(loaded), len(templates))
+	}
+}
+
+func TestLoadAllSecurityConceptsOverridesBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	writePack(t, dir, "override.toml", `
+pack_name = "override-pack"
+
+[[concepts]]
+name = "active"
+description = "overridden description"
+synonyms = ["overridden"]
+`)
+
+	merged, err := concepts.LoadAllSecurityConcepts(dir)
+	if err != nil {
+		t.Fatalf("LoadAllSecurityConcepts() error = %v", err)
+	}
+
+	var found bool
+	for _, c := range merged {
+		if c.Name == "active" {
+			found = true
+			if c.Description != "overridden description" || c.Pack != "override-pack" {
+				t.Errorf("expected pack override to win, got %+v", c)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected 'active' concept to still be present")
+	}
+}
@@ -0,0 +1,193 @@
+// Package config loads a project's .solidair.toml, the file-exclusion and
+// per-query override settings that turn solidair from an always-scan-
+// everything tool into one usable on real Cairo repos with vendored code,
+// generated files, and known-good patterns.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+)
+
+// DefaultFilename is the config file solidair looks for in a project root
+// when --config isn't given.
+const DefaultFilename = ".solidair.toml"
+
+// QueryOverride customizes one query by its Name: metadata (see
+// ExtractQueryMetadata), letting a config file turn off a noisy query or
+// override its reported severity without editing the .scm file itself.
+type QueryOverride struct {
+	Enabled  *bool  `toml:"enabled,omitempty"`
+	Severity string `toml:"severity,omitempty"`
+}
+
+// Config holds path/extension/string exclusion rules and per-query
+// overrides, loaded from a project's .solidair.toml (or --config path).
+type Config struct {
+	// BlacklistedPaths and ExcludePaths are substrings or glob patterns
+	// matched against a discovered file's path; either list excludes it
+	// from scanning. They're kept as two fields (rather than merged) so a
+	// config file can group "third-party code we don't own" separately
+	// from "generated code we do".
+	BlacklistedPaths []string `toml:"blacklisted_paths,omitempty"`
+	ExcludePaths     []string `toml:"exclude_paths,omitempty"`
+
+	// BlacklistedExtensions excludes files whose final extension
+	// (filepath.Ext, e.g. ".generated") equals an entry. A multi-dot suffix
+	// such as ".g.cairo" never matches, since its final extension is
+	// ".cairo".
+	BlacklistedExtensions []string `toml:"blacklisted_extensions,omitempty"`
+
+	// BlacklistedStrings drops a QueryResult whose Code matches any entry -
+	// a literal substring by default, or a regular expression when
+	// BlacklistedStringsRegex is set.
+	BlacklistedStrings      []string `toml:"blacklisted_strings,omitempty"`
+	BlacklistedStringsRegex bool     `toml:"blacklisted_strings_regex,omitempty"`
+
+	// Queries overrides per-query enable/disable and severity, keyed by the
+	// query's Name: metadata.
+	Queries map[string]QueryOverride `toml:"queries,omitempty"`
+
+	stringMatchers []stringMatcher
+}
+
+type stringMatcher struct {
+	literal string
+	re      *regexp.Regexp
+}
+
+// Load reads and parses the config file at path. A missing file isn't an
+// error: it returns an empty, permissive Config, so callers can always do
+// cfg, err := config.Load(path) and use cfg whether or not the project has
+// adopted a .solidair.toml yet.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &Config{}, nil
+		}
+		return nil, fmt.Errorf("error reading config %s: %w", path, err)
+	}
+
+	var cfg Config
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("error parsing config %s: %w", path, err)
+	}
+	if err := cfg.compileStringMatchers(); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+func (c *Config) compileStringMatchers() error {
+	c.stringMatchers = make([]stringMatcher, len(c.BlacklistedStrings))
+	for i, s := range c.BlacklistedStrings {
+		if c.BlacklistedStringsRegex {
+			re, err := regexp.Compile(s)
+			if err != nil {
+				return fmt.Errorf("error compiling blacklisted_strings regex %q: %w", s, err)
+			}
+			c.stringMatchers[i] = stringMatcher{re: re}
+			continue
+		}
+		c.stringMatchers[i] = stringMatcher{literal: s}
+	}
+	return nil
+}
+
+// SkipPath reports whether path should be excluded from scanning: it
+// matches a BlacklistedPaths/ExcludePaths entry (as a glob pattern or a
+// plain substring), or its extension is in BlacklistedExtensions. A nil
+// Config never skips anything.
+func (c *Config) SkipPath(path string) bool {
+	if c == nil {
+		return false
+	}
+
+	for _, pattern := range c.BlacklistedPaths {
+		if pathMatches(pattern, path) {
+			return true
+		}
+	}
+	for _, pattern := range c.ExcludePaths {
+		if pathMatches(pattern, path) {
+			return true
+		}
+	}
+
+	ext := filepath.Ext(path)
+	for _, blocked := range c.BlacklistedExtensions {
+		if ext == blocked {
+			return true
+		}
+	}
+	return false
+}
+
+func pathMatches(pattern, path string) bool {
+	if pattern == "" {
+		return false
+	}
+	if matched, _ := filepath.Match(pattern, path); matched {
+		return true
+	}
+	// filepath.Match never lets "*" cross a "/", so a pattern like
+	// "*.g.cairo" needs to be tried against the base name too in order to
+	// match a generated file nested under a subdirectory.
+	if matched, _ := filepath.Match(pattern, filepath.Base(path)); matched {
+		return true
+	}
+	return strings.Contains(path, pattern)
+}
+
+// SkipCode reports whether code matches a blacklisted_strings entry and
+// should be dropped from RunQueries' results. A nil Config never skips
+// anything.
+func (c *Config) SkipCode(code string) bool {
+	if c == nil {
+		return false
+	}
+	for _, m := range c.stringMatchers {
+		if m.re != nil {
+			if m.re.MatchString(code) {
+				return true
+			}
+			continue
+		}
+		if strings.Contains(code, m.literal) {
+			return true
+		}
+	}
+	return false
+}
+
+// QueryEnabled reports whether queryName should run, honoring an explicit
+// Queries override. A query not mentioned in the config defaults to
+// enabled, and a nil Config enables everything.
+func (c *Config) QueryEnabled(queryName string) bool {
+	if c == nil {
+		return true
+	}
+	if o, ok := c.Queries[queryName]; ok && o.Enabled != nil {
+		return *o.Enabled
+	}
+	return true
+}
+
+// SeverityOverride returns the config-provided severity for queryName and
+// whether one was set. A nil Config never overrides anything.
+func (c *Config) SeverityOverride(queryName string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	if o, ok := c.Queries[queryName]; ok && o.Severity != "" {
+		return o.Severity, true
+	}
+	return "", false
+}
@@ -0,0 +1,122 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/pkg/config"
+)
+
+func writeConfig(t *testing.T, dir, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, config.DefaultFilename)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing config file: %v", err)
+	}
+	return path
+}
+
+func TestLoadMissingFileReturnsPermissiveConfig(t *testing.T) {
+	cfg, err := config.Load(filepath.Join(t.TempDir(), "nonexistent.toml"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.SkipPath("vendor/foo.cairo") {
+		t.Errorf("expected empty Config to skip nothing")
+	}
+	if cfg.SkipCode("anything") {
+		t.Errorf("expected empty Config to filter nothing")
+	}
+	if !cfg.QueryEnabled("any_query") {
+		t.Errorf("expected empty Config to enable every query")
+	}
+}
+
+func TestSkipPath(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, `
+blacklisted_paths = ["vendor/"]
+exclude_paths = ["*.g.cairo"]
+blacklisted_extensions = [".generated"]
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	cases := map[string]bool{
+		"vendor/openzeppelin/erc20.cairo": true,
+		"src/token.g.cairo":               true,
+		"src/token.generated":             true,
+		"src/token.cairo":                 false,
+	}
+	for p, want := range cases {
+		if got := cfg.SkipPath(p); got != want {
+			t.Errorf("SkipPath(%q) = %v, want %v", p, got, want)
+		}
+	}
+}
+
+func TestSkipCodeLiteralAndRegex(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, `
+blacklisted_strings = ["is_known_safe_pattern"]
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.SkipCode("let x = is_known_safe_pattern();") {
+		t.Errorf("expected literal blacklisted_strings match to be skipped")
+	}
+	if cfg.SkipCode("let x = unrelated();") {
+		t.Errorf("expected unrelated code not to be skipped")
+	}
+
+	regexDir := t.TempDir()
+	regexPath := writeConfig(t, regexDir, `
+blacklisted_strings = ["^test_.*"]
+blacklisted_strings_regex = true
+`)
+	regexCfg, err := config.Load(regexPath)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !regexCfg.SkipCode("test_helper_fn") {
+		t.Errorf("expected regex blacklisted_strings match to be skipped")
+	}
+	if regexCfg.SkipCode("helper_fn") {
+		t.Errorf("expected non-matching code not to be skipped")
+	}
+}
+
+func TestQueryOverrides(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfig(t, dir, `
+[queries.unchecked_division]
+enabled = false
+
+[queries.reentrancy]
+severity = "critical"
+`)
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.QueryEnabled("unchecked_division") {
+		t.Errorf("expected unchecked_division to be disabled")
+	}
+	if !cfg.QueryEnabled("reentrancy") {
+		t.Errorf("expected reentrancy to default to enabled")
+	}
+
+	if sev, ok := cfg.SeverityOverride("reentrancy"); !ok || sev != "critical" {
+		t.Errorf("SeverityOverride(reentrancy) = (%q, %v), want (critical, true)", sev, ok)
+	}
+	if _, ok := cfg.SeverityOverride("unchecked_division"); ok {
+		t.Errorf("expected no severity override for unchecked_division")
+	}
+}
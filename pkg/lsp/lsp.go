@@ -0,0 +1,113 @@
+// Package lsp implements just enough of the Language Server Protocol's wire
+// format - Content-Length-framed JSON-RPC 2.0 messages over stdio - to let
+// cmd's lsp subcommand talk to an editor. It knows nothing about
+// solidair-specific methods like textDocument/publishDiagnostics; that logic
+// lives in cmd/lsp.go, which reads and writes Message values through this
+// package.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Message is a JSON-RPC 2.0 request, response, or notification. Requests and
+// notifications set Method (and Params); notifications omit ID. Responses
+// set ID and exactly one of Result or Error.
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object, following the same field names the
+// protocol's own error responses use.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Standard JSON-RPC error codes used by cmd/lsp.go.
+const (
+	ErrParse          = -32700
+	ErrInvalidReq     = -32600
+	ErrMethodNotFound = -32601
+)
+
+// ReadMessage reads one Content-Length-framed JSON-RPC message from r,
+// blocking until the headers and body have both arrived.
+func ReadMessage(r *bufio.Reader) (*Message, error) {
+	length := -1
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing Content-Length header %q: %w", line, err)
+			}
+			length = n
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message had no Content-Length header")
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+
+	var msg Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, fmt.Errorf("error parsing LSP message: %w", err)
+	}
+	return &msg, nil
+}
+
+// WriteMessage encodes v as the body of a Content-Length-framed message and
+// writes it to w.
+func WriteMessage(w io.Writer, v *Message) error {
+	v.JSONRPC = "2.0"
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("error encoding LSP message: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(data)); err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// Response builds a success response to the request with id.
+func Response(id json.RawMessage, result interface{}) *Message {
+	return &Message{ID: id, Result: result}
+}
+
+// ResponseError builds an error response to the request with id.
+func ResponseError(id json.RawMessage, code int, message string) *Message {
+	return &Message{ID: id, Error: &Error{Code: code, Message: message}}
+}
+
+// Notification builds a server-initiated notification carrying params.
+func Notification(method string, params interface{}) (*Message, error) {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding %s params: %w", method, err)
+	}
+	return &Message{Method: method, Params: data}, nil
+}
@@ -0,0 +1,54 @@
+package project
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jeffrydegrande/solidair/types"
+)
+
+// cacheFile is the gob-encoded shape of .solidair/cache.bin: a flat map
+// from "<file_sha256>:<VariableInfo.Prompt()>" to the embedding computed for that
+// variable the last time its file had that content.
+type cacheFile struct {
+	Entries map[string]types.Embedding
+}
+
+// loadCache reads path's cache, returning an empty map if it doesn't exist
+// yet.
+func loadCache(path string) (map[string]types.Embedding, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return make(map[string]types.Embedding), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var cf cacheFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&cf); err != nil {
+		return nil, fmt.Errorf("error decoding cache: %w", err)
+	}
+	return cf.Entries, nil
+}
+
+// saveCache writes entries to path, creating its parent directory if
+// needed.
+func saveCache(path string, entries map[string]types.Embedding) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(cacheFile{Entries: entries}); err != nil {
+		return fmt.Errorf("error encoding cache: %w", err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing cache: %w", err)
+	}
+	return nil
+}
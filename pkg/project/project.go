@@ -0,0 +1,271 @@
+// Package project discovers and parses a whole Cairo project — a
+// Scarb.toml-rooted tree, or a plain directory of .cairo files — so
+// matchCmd and analyzeCmd can work across every file at once instead of
+// one at a time, sharing a single on-disk embedding cache across the run.
+package project
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/embedding"
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/types"
+	"github.com/jeffrydegrande/solidair/variables"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// CacheFile is where a project's embedding cache is persisted, relative to
+// the project root.
+const CacheFile = ".solidair/cache.bin"
+
+// scarbManifest is the subset of Scarb.toml (Cairo's package manifest) we
+// care about: just enough to name the project in output.
+type scarbManifest struct {
+	Package struct {
+		Name string `toml:"name"`
+	} `toml:"package"`
+}
+
+// Project is a parsed, project-wide view over a Scarb.toml-rooted Cairo
+// tree or a plain directory of .cairo files: every file's variables in one
+// table, plus the embedding cache shared across them.
+type Project struct {
+	Root      string                         // as passed to Load: a file or a directory
+	Name      string                         // package name from Scarb.toml, if one was found
+	Files     []string                       // every .cairo file the project spans
+	Variables []variables.ExtractedVariables // one entry per file, Filename set
+
+	cachePath string
+	fileHash  map[string]string // file path -> sha256 of its contents, for cache keys
+}
+
+// DiscoverFiles resolves path to the list of .cairo files a project spans:
+// path itself if it's a single file, or every .cairo file under it if it's
+// a directory.
+func DiscoverFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.HasSuffix(p, ".cairo") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking %s: %w", path, err)
+	}
+	return files, nil
+}
+
+// findScarbManifest reads the package name out of root/Scarb.toml, if root
+// is a directory that has one. A missing manifest isn't an error: plenty of
+// Cairo trees this tool runs on are just a bag of .cairo files.
+func findScarbManifest(root string) (string, error) {
+	info, err := os.Stat(root)
+	if err != nil || !info.IsDir() {
+		return "", nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "Scarb.toml"))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading Scarb.toml: %w", err)
+	}
+
+	var manifest scarbManifest
+	if err := toml.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("error parsing Scarb.toml: %w", err)
+	}
+	return manifest.Package.Name, nil
+}
+
+// fileParse is one file's parse result, produced by a Load worker.
+type fileParse struct {
+	path string
+	vars []types.VariableInfo
+	hash string
+	err  error
+}
+
+// Load discovers path's .cairo files and parses them in parallel with up to
+// workers goroutines, building the project's unified variable table. The
+// embedding cache isn't touched until EmbedVariables is called.
+func Load(path string, workers int) (*Project, error) {
+	files, err := DiscoverFiles(path)
+	if err != nil {
+		return nil, err
+	}
+	name, err := findScarbManifest(path)
+	if err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	paths := make(chan string)
+	results := make(chan fileParse)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for p := range paths {
+				results <- parseFile(p)
+			}
+		}()
+	}
+
+	go func() {
+		defer close(paths)
+		for _, f := range files {
+			paths <- f
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	root := path
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		root = filepath.Dir(path)
+	}
+
+	p := &Project{
+		Root:      path,
+		Name:      name,
+		Files:     files,
+		cachePath: filepath.Join(root, CacheFile),
+		fileHash:  make(map[string]string),
+	}
+
+	var firstErr error
+	for r := range results {
+		if r.err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("error parsing %s: %w", r.path, r.err)
+			}
+			continue
+		}
+		p.fileHash[r.path] = r.hash
+		p.Variables = append(p.Variables, variables.ExtractedVariables{
+			Filename:  r.path,
+			Variables: r.vars,
+		})
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	return p, nil
+}
+
+func parseFile(path string) fileParse {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return fileParse{path: path, err: err}
+	}
+
+	tree, err := cairo.Parse(source)
+	if err != nil {
+		return fileParse{path: path, err: err}
+	}
+	defer tree.Close()
+
+	backend, ok := languages.ForExtension(".cairo")
+	if !ok {
+		return fileParse{path: path, err: fmt.Errorf("no cairo language backend registered")}
+	}
+
+	extracted, err := variables.ExtractVariables(source, tree, backend)
+	if err != nil {
+		return fileParse{path: path, err: err}
+	}
+
+	sum := sha256.Sum256(source)
+	return fileParse{path: path, vars: extracted.Variables, hash: hex.EncodeToString(sum[:])}
+}
+
+// EmbedVariables embeds every variable in the project whose (file hash,
+// prompt) isn't already in the on-disk cache, in a single batched provider
+// call, and returns a prompt -> embedding map ready to seed an
+// EmbeddingMatcher's cache - keyed by VariableInfo.Prompt(), the same text
+// EmbeddingMatcher.GetVariableEmbedding embeds, so a cache hit here is also a
+// cache hit there. The updated cache is persisted back to
+// <root>/.solidair/cache.bin before returning.
+func (p *Project) EmbedVariables(ctx context.Context, provider embedding.EmbeddingProvider) (map[string]types.Embedding, error) {
+	cache, err := loadCache(p.cachePath)
+	if err != nil {
+		return nil, fmt.Errorf("error loading embedding cache: %w", err)
+	}
+
+	byPrompt := make(map[string]types.Embedding)
+
+	type staleEntry struct {
+		key    string
+		prompt string
+	}
+	var stale []staleEntry
+	staleKeys := make(map[string]bool)
+	for _, fv := range p.Variables {
+		for _, v := range fv.Variables {
+			prompt := v.Prompt()
+			key := p.fileHash[fv.Filename] + ":" + prompt
+			if e, ok := cache[key]; ok {
+				byPrompt[prompt] = e
+				continue
+			}
+			if staleKeys[key] {
+				continue
+			}
+			staleKeys[key] = true
+			stale = append(stale, staleEntry{key: key, prompt: prompt})
+		}
+	}
+
+	if len(stale) > 0 {
+		texts := make([]string, len(stale))
+		for i, e := range stale {
+			texts[i] = e.prompt
+		}
+		embeddings, err := provider.Embed(ctx, texts)
+		if err != nil {
+			return nil, fmt.Errorf("error embedding %d variables: %w", len(stale), err)
+		}
+
+		for i, e := range stale {
+			cache[e.key] = embeddings[i]
+			byPrompt[e.prompt] = embeddings[i]
+		}
+
+		if err := saveCache(p.cachePath, cache); err != nil {
+			return nil, fmt.Errorf("error saving embedding cache: %w", err)
+		}
+	}
+
+	return byPrompt, nil
+}
@@ -0,0 +1,244 @@
+// Package report renders analyze and match results as SARIF 2.1.0 or
+// line-delimited JSON, so solidair's output can feed CI dashboards and
+// other SARIF consumers instead of only a human reading stdout.
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const sarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+// Finding is one reportable result, normalized so SARIF/JSON rendering
+// doesn't need to know whether it came from RunQueries (a vulnerability
+// pattern match) or MatchVariables (a variable/concept match). RuleID is the
+// query name for the former and the concept name for the latter.
+type Finding struct {
+	RuleID          string
+	FilePath        string
+	Description     string
+	LineNumber      uint32
+	Code            string  // matched code span, or the variable name for a concept match
+	SimilarityScore float32 // set for concept matches, zero for query findings
+	Severity        string  // set from a config.Config query override or a query's own Severity: comment, empty otherwise
+
+	// EndLine, Column, StartByte, and EndByte locate Code precisely within
+	// FilePath, recovered from the originating tree-sitter node; SARIF uses
+	// them to fill out Region beyond just its required startLine. All zero
+	// for a concept match, which has no matched span of its own.
+	EndLine   uint32
+	Column    uint32
+	StartByte uint32
+	EndByte   uint32
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string           `json:"id"`
+	ShortDescription     *sarifMessage    `json:"shortDescription,omitempty"`
+	DefaultConfiguration *sarifRuleConfig `json:"defaultConfiguration,omitempty"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID              string                 `json:"ruleId"`
+	Message             sarifMessage           `json:"message"`
+	Locations           []sarifLocation        `json:"locations"`
+	PartialFingerprints map[string]string      `json:"partialFingerprints"`
+	Properties          map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine   uint32 `json:"startLine"`
+	StartColumn uint32 `json:"startColumn,omitempty"`
+	EndLine     uint32 `json:"endLine,omitempty"`
+	ByteOffset  uint32 `json:"byteOffset,omitempty"`
+	ByteLength  uint32 `json:"byteLength,omitempty"`
+}
+
+// SARIF renders findings as a SARIF 2.1.0 log. Each result carries a
+// partialFingerprint derived from its matched code span, so GitHub code
+// scanning and other SARIF consumers can dedupe the same finding across
+// runs even when its line number shifts.
+func SARIF(findings []Finding) ([]byte, error) {
+	rules := make(map[string]bool)
+	var sarifRules []sarifRule
+	sarifResults := make([]sarifResult, 0, len(findings))
+
+	for _, f := range findings {
+		if !rules[f.RuleID] {
+			rules[f.RuleID] = true
+			sarifRules = append(sarifRules, newSARIFRule(f))
+		}
+
+		var properties map[string]interface{}
+		if f.SimilarityScore > 0 {
+			properties = map[string]interface{}{"similarityScore": f.SimilarityScore}
+		}
+		if f.Severity != "" {
+			if properties == nil {
+				properties = map[string]interface{}{}
+			}
+			properties["severity"] = f.Severity
+		}
+
+		sarifResults = append(sarifResults, sarifResult{
+			RuleID:  f.RuleID,
+			Message: sarifMessage{Text: f.Description},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: f.FilePath},
+					Region: sarifRegion{
+						StartLine:   f.LineNumber,
+						StartColumn: f.Column,
+						EndLine:     f.EndLine,
+						ByteOffset:  f.StartByte,
+						ByteLength:  f.EndByte - f.StartByte,
+					},
+				},
+			}},
+			PartialFingerprints: map[string]string{
+				"primaryLocationLineHash": codeFingerprint(f.Code),
+			},
+			Properties: properties,
+		})
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "solidair", Rules: sarifRules}},
+			Results: sarifResults,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding SARIF: %w", err)
+	}
+	return data, nil
+}
+
+// newSARIFRule builds the reporting descriptor for f's RuleID from its first
+// occurrence: f.Description becomes shortDescription, and f.Severity (a
+// config.Config override or the query's own Severity: comment) becomes
+// defaultConfiguration.level via sarifLevel, so GitHub code scanning can
+// sort and filter by severity without reading every result's properties.
+func newSARIFRule(f Finding) sarifRule {
+	rule := sarifRule{ID: f.RuleID}
+	if f.Description != "" {
+		rule.ShortDescription = &sarifMessage{Text: f.Description}
+	}
+	if level, ok := sarifLevel(f.Severity); ok {
+		rule.DefaultConfiguration = &sarifRuleConfig{Level: level}
+	}
+	return rule
+}
+
+// sarifLevel maps a free-form severity string to one of SARIF's four result
+// levels, so callers can write whatever severity casing/wording they like
+// in a Severity: comment or config.Config override.
+func sarifLevel(severity string) (string, bool) {
+	switch strings.ToLower(severity) {
+	case "critical", "high", "error":
+		return "error", true
+	case "medium", "warning":
+		return "warning", true
+	case "low", "info", "note":
+		return "note", true
+	case "":
+		return "", false
+	default:
+		return "warning", true
+	}
+}
+
+// jsonFinding is the line-delimited JSON shape for one finding.
+type jsonFinding struct {
+	RuleID          string  `json:"rule_id"`
+	FilePath        string  `json:"file_path"`
+	Description     string  `json:"description,omitempty"`
+	LineNumber      uint32  `json:"line_number"`
+	EndLine         uint32  `json:"end_line,omitempty"`
+	Column          uint32  `json:"column,omitempty"`
+	Code            string  `json:"code"`
+	SimilarityScore float32 `json:"similarity_score,omitempty"`
+	Severity        string  `json:"severity,omitempty"`
+}
+
+// JSONLines writes findings as line-delimited JSON to w, one object per
+// line, so they can be piped into jq or another process without buffering a
+// whole array.
+func JSONLines(w io.Writer, findings []Finding) error {
+	enc := json.NewEncoder(w)
+	for _, f := range findings {
+		err := enc.Encode(jsonFinding{
+			RuleID:          f.RuleID,
+			FilePath:        f.FilePath,
+			Description:     f.Description,
+			LineNumber:      f.LineNumber,
+			EndLine:         f.EndLine,
+			Column:          f.Column,
+			Code:            f.Code,
+			SimilarityScore: f.SimilarityScore,
+			Severity:        f.Severity,
+		})
+		if err != nil {
+			return fmt.Errorf("error encoding finding: %w", err)
+		}
+	}
+	return nil
+}
+
+// codeFingerprint hashes a finding's matched code span for SARIF's
+// partialFingerprints, truncated to 16 hex chars to match the length GitHub
+// code scanning's own primaryLocationLineHash values use.
+func codeFingerprint(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])[:16]
+}
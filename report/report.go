@@ -0,0 +1,145 @@
+// Package report renders scan results in formats other tooling can consume:
+// SARIF 2.1.0 for GitHub code scanning and IDE extensions, and
+// line-delimited JSON for piping into other processes.
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/jeffrydegrande/solidair/scanner"
+)
+
+const sarifSchema = "https://raw.githubusercontent.com/oasis-tcs/sarif-spec/master/Schemata/sarif-schema-2.1.0.json"
+
+// sarifLog mirrors the subset of the SARIF 2.1.0 object model this tool produces.
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name  string      `json:"name"`
+	Rules []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID string `json:"id"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine uint32 `json:"startLine"`
+}
+
+// SARIF renders results as a SARIF 2.1.0 log.
+func SARIF(results []scanner.QueryResult) ([]byte, error) {
+	rules := make(map[string]bool)
+	var sarifRules []sarifRule
+	sarifResults := make([]sarifResult, 0, len(results))
+
+	for _, r := range results {
+		if !rules[r.QueryName] {
+			rules[r.QueryName] = true
+			sarifRules = append(sarifRules, sarifRule{ID: r.QueryName})
+		}
+
+		var properties map[string]interface{}
+		if r.SimilarityScore > 0 {
+			properties = map[string]interface{}{"similarityScore": r.SimilarityScore}
+		}
+
+		sarifResults = append(sarifResults, sarifResult{
+			RuleID:  r.QueryName,
+			Message: sarifMessage{Text: r.Description},
+			Locations: []sarifLocation{{
+				PhysicalLocation: sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifactLocation{URI: r.FilePath},
+					Region:           sarifRegion{StartLine: r.LineNumber},
+				},
+			}},
+			Properties: properties,
+		})
+	}
+
+	log := sarifLog{
+		Schema:  sarifSchema,
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "solidair", Rules: sarifRules}},
+			Results: sarifResults,
+		}},
+	}
+
+	data, err := json.MarshalIndent(log, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding SARIF: %w", err)
+	}
+	return data, nil
+}
+
+// jsonResult is the line-delimited JSON shape for one finding.
+type jsonResult struct {
+	QueryName       string  `json:"query_name"`
+	QueryFile       string  `json:"query_file"`
+	FilePath        string  `json:"file_path"`
+	Description     string  `json:"description,omitempty"`
+	LineNumber      uint32  `json:"line_number"`
+	Code            string  `json:"code"`
+	SimilarityScore float32 `json:"similarity_score,omitempty"`
+}
+
+// JSONLines writes results as line-delimited JSON to w, one object per line,
+// so they can be piped into jq or another process without buffering a whole array.
+func JSONLines(w io.Writer, results []scanner.QueryResult) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		err := enc.Encode(jsonResult{
+			QueryName:       r.QueryName,
+			QueryFile:       r.QueryFile,
+			FilePath:        r.FilePath,
+			Description:     r.Description,
+			LineNumber:      r.LineNumber,
+			Code:            r.Code,
+			SimilarityScore: r.SimilarityScore,
+		})
+		if err != nil {
+			return fmt.Errorf("error encoding result: %w", err)
+		}
+	}
+	return nil
+}
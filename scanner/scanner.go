@@ -0,0 +1,296 @@
+// Package scanner compiles Tree-sitter query templates once and runs them
+// across many Cairo source files, replacing the per-invocation recompilation
+// and leaking query/cursor resources that RunQueries did when it compiled
+// every .scm file for every call.
+package scanner
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"unsafe"
+
+	cairo "github.com/jeffrydegrande/solidair/cairo"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// QueryResult represents a finding from running a compiled query against a file.
+type QueryResult struct {
+	QueryName   string
+	QueryFile   string
+	FilePath    string
+	Description string
+	LineNumber  uint32
+	Code        string
+
+	// SimilarityScore is set when this result came from a templated query
+	// resolved by concept matching rather than a plain static query, and is
+	// zero otherwise.
+	SimilarityScore float32
+}
+
+// compiledQuery is a query compiled once and reused across files.
+type compiledQuery struct {
+	name        string
+	description string
+	source      string
+	query       *tree_sitter.Query
+}
+
+var (
+	descRegex = regexp.MustCompile(`(?m)^;\s*Description:\s*(.+)$`)
+	nameRegex = regexp.MustCompile(`(?m)^;\s*Name:\s*(.+)$`)
+)
+
+// Scanner parses and compiles every query template once at load time, holds
+// a pool of QueryCursors for reuse (each tree-sitter parser/cursor is not
+// safe to share across goroutines, so workers borrow their own), and can
+// walk a directory tree of Cairo sources in parallel.
+type Scanner struct {
+	lang    *tree_sitter.Language
+	static  []*compiledQuery
+	cursors sync.Pool
+
+	mu        sync.Mutex
+	processed map[string]*compiledQuery // keyed by sha256 of the processed query text
+}
+
+// NewScanner loads and compiles every .scm file under queryDir.
+func NewScanner(queryDir string) (*Scanner, error) {
+	s := &Scanner{
+		lang:      tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language())),
+		processed: make(map[string]*compiledQuery),
+	}
+	s.cursors.New = func() any { return tree_sitter.NewQueryCursor() }
+
+	err := filepath.WalkDir(queryDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".scm") {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading query file %s: %w", path, err)
+		}
+
+		relPath, err := filepath.Rel(queryDir, path)
+		if err != nil {
+			return fmt.Errorf("error getting relative path for %s: %w", path, err)
+		}
+
+		cq, err := s.compile(relPath, string(content))
+		if err != nil {
+			// A single bad query shouldn't prevent the rest from loading.
+			return nil
+		}
+		s.static = append(s.static, cq)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking query directory: %w", err)
+	}
+
+	return s, nil
+}
+
+// compile parses metadata and pattern out of raw .scm content and compiles
+// the Tree-sitter query once.
+func (s *Scanner) compile(source, content string) (*compiledQuery, error) {
+	name := ""
+	if matches := nameRegex.FindStringSubmatch(content); len(matches) > 1 {
+		name = matches[1]
+	}
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(source), filepath.Ext(source))
+	}
+
+	description := ""
+	if matches := descRegex.FindStringSubmatch(content); len(matches) > 1 {
+		description = matches[1]
+	}
+
+	pattern := extractQueryPattern(content)
+	query, err := tree_sitter.NewQuery(s.lang, pattern)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling query %s: %w", source, err)
+	}
+
+	return &compiledQuery{name: name, description: description, source: source, query: query}, nil
+}
+
+// CompileProcessed compiles a templated query after parameter substitution,
+// caching the result by a hash of the processed text so the same
+// concept-matched query recurring across files isn't recompiled.
+func (s *Scanner) CompileProcessed(name, processedQuery string) (*compiledQuery, error) {
+	hash := sha256.Sum256([]byte(processedQuery))
+	key := hex.EncodeToString(hash[:])
+
+	s.mu.Lock()
+	if cq, ok := s.processed[key]; ok {
+		s.mu.Unlock()
+		return cq, nil
+	}
+	s.mu.Unlock()
+
+	query, err := tree_sitter.NewQuery(s.lang, extractQueryPattern(processedQuery))
+	if err != nil {
+		return nil, fmt.Errorf("error compiling processed query %s: %w", name, err)
+	}
+	cq := &compiledQuery{name: name, source: name, query: query}
+
+	s.mu.Lock()
+	s.processed[key] = cq
+	s.mu.Unlock()
+
+	return cq, nil
+}
+
+// extractQueryPattern removes comments and metadata, leaving just the query pattern.
+func extractQueryPattern(content string) string {
+	lines := strings.Split(content, "\n")
+	var queryLines []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+		queryLines = append(queryLines, line)
+	}
+	return strings.Join(queryLines, "\n")
+}
+
+// ScanSource runs every statically-loaded query against one parsed file,
+// borrowing a QueryCursor from the pool instead of allocating and leaking one
+// per query.
+func (s *Scanner) ScanSource(filePath string, source []byte, tree *tree_sitter.Tree) []QueryResult {
+	var results []QueryResult
+	root := tree.RootNode()
+
+	for _, cq := range s.static {
+		results = append(results, s.run(cq, filePath, source, root)...)
+	}
+	return results
+}
+
+// ScanDir walks root in parallel with the given number of workers, parsing
+// and scanning every .cairo file it finds, and streams results through the
+// returned channel as they're found. The channel is closed once every file
+// has been scanned or ctx is cancelled. Scan errors (a file that fails to
+// read or parse) are logged to errCh rather than aborting the whole walk.
+func (s *Scanner) ScanDir(ctx context.Context, root string, workers int) (<-chan QueryResult, <-chan error) {
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make(chan QueryResult)
+	errs := make(chan error, workers)
+	paths := make(chan string)
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+
+				if err := s.scanFile(ctx, path, results); err != nil {
+					select {
+					case errs <- err:
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(paths)
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() || !strings.HasSuffix(path, ".cairo") {
+				return nil
+			}
+			select {
+			case paths <- path:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			return nil
+		})
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+		close(errs)
+	}()
+
+	return results, errs
+}
+
+// scanFile parses one file and sends its matches to results.
+func (s *Scanner) scanFile(ctx context.Context, path string, results chan<- QueryResult) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	parser := tree_sitter.NewParser()
+	defer parser.Close()
+	if err := parser.SetLanguage(s.lang); err != nil {
+		return fmt.Errorf("error setting language for %s: %w", path, err)
+	}
+
+	tree := parser.Parse(source, nil)
+	defer tree.Close()
+
+	for _, r := range s.ScanSource(path, source, tree) {
+		select {
+		case results <- r:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (s *Scanner) run(cq *compiledQuery, filePath string, source []byte, root *tree_sitter.Node) []QueryResult {
+	qc := s.cursors.Get().(*tree_sitter.QueryCursor)
+	defer s.cursors.Put(qc)
+
+	var results []QueryResult
+	matches := qc.Matches(cq.query, root, source)
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		for _, capture := range match.Captures {
+			node := capture.Node
+			results = append(results, QueryResult{
+				QueryName:   cq.name,
+				QueryFile:   cq.source,
+				FilePath:    filePath,
+				Description: cq.description,
+				LineNumber:  uint32(node.StartPosition().Row) + 1,
+				Code:        string(source[node.StartByte():node.EndByte()]),
+			})
+			break // one capture per match is enough to report the finding
+		}
+	}
+	return results
+}
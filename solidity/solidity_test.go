@@ -0,0 +1,63 @@
+package solidity_test
+
+import (
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/solidity"
+)
+
+func TestLanguage(t *testing.T) {
+	lang := solidity.Language()
+	if lang == nil {
+		t.Errorf("Language() returned nil")
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  []byte
+		wantErr bool
+	}{
+		{
+			name:    "Empty source",
+			source:  []byte(""),
+			wantErr: false,
+		},
+		{
+			name: "Simple contract",
+			source: []byte(`
+				contract Vault {
+					mapping(address => uint256) balances;
+
+					function withdraw(uint256 amount) public {
+						balances[msg.sender] -= amount;
+					}
+				}
+			`),
+			wantErr: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tree, err := solidity.Parse(tt.source)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if tree == nil {
+				t.Errorf("Parse() returned nil tree")
+				return
+			}
+
+			root := tree.RootNode()
+			if root == nil {
+				t.Errorf("Parse() returned tree with nil root node")
+			}
+		})
+	}
+}
@@ -0,0 +1,479 @@
+// Package taint implements a lightweight intra-procedural taint analysis,
+// the complement to cmd.RunQueries' purely syntactic pattern matching: a
+// query file whose header ExtractQueryMetadata parses as "Kind: taint"
+// declares @source, @sink, and (optionally) @sanitizer captures instead of
+// being run as an ordinary pattern match. Run builds a def-use graph over
+// each Cairo function - let bindings and parameters are defs, identifier
+// references are uses - seeds a def as tainted when a @source capture falls
+// within its declaration, and propagates taint through the graph with a
+// worklist until fixpoint. A @sink capture whose value is still tainted
+// after that fixpoint, and that isn't itself downstream of a @sanitizer, is
+// reported as a Finding.
+//
+// This is the rounding/precision-bug case the package doc references:
+// RunQueries can flag "a divisor exists" syntactically, but can't tell
+// whether the value dividing it actually traces back to attacker-controlled
+// input without first being validated - that's what the def-use graph and
+// worklist below are for.
+package taint
+
+import (
+	"fmt"
+
+	"github.com/jeffrydegrande/solidair/languages"
+	tree_sitter "github.com/tree-sitter/go-tree-sitter"
+)
+
+// functionQuery captures every function_item along with its own name, the
+// unit this package's def-use graph is scoped to.
+const functionQuery = `
+(function_item
+  name: (identifier) @fn_name) @fn
+`
+
+// letDefQuery captures every let/let-mut binding's name and value
+// expression. The enclosing function isn't capturable here (a let can nest
+// arbitrarily deep inside blocks), so Run assigns each one to a function by
+// byte range instead.
+const letDefQuery = `
+(let_declaration
+  pattern: (identifier) @name
+  value: (_) @value) @let
+`
+
+// paramDefQuery captures every function parameter's name. A parameter has
+// no value expression of its own - it's tainted only when a @source capture
+// matches the parameter declaration directly (an untrusted external input).
+const paramDefQuery = `
+(parameter
+  pattern: (identifier) @name) @param
+`
+
+// Query is one "Kind: taint" query file's compiled form, carrying the same
+// metadata ExtractQueryMetadata already parses out of its header comments
+// for an ordinary pattern-match query.
+type Query struct {
+	Name        string
+	File        string
+	Description string
+	Severity    string
+	// Pattern is the .scm pattern with its header comments stripped,
+	// annotated with @source, @sink, and optionally @sanitizer captures.
+	Pattern string
+}
+
+// Finding is a tainted value reaching a @sink without passing through a
+// @sanitizer, in the same shape cmd.QueryResult uses so a caller can adapt
+// it into the existing reporting pipeline unchanged.
+type Finding struct {
+	QueryName          string
+	QueryFile          string
+	Description        string
+	LineNumber         uint32
+	EndLine            uint32
+	Column             uint32
+	StartByte          uint32
+	EndByte            uint32
+	Code               string
+	Severity           string
+	EnclosingConstruct string
+	// Function is the name of the enclosing Cairo function the tainted
+	// value reached the sink in. This intra-procedural pass doesn't use it
+	// for anything itself, but it's the key a later inter-procedural
+	// summary (does taint reach one of this function's arguments, does
+	// its return value carry taint out to its callers) would index by.
+	Function string
+}
+
+// def is one let binding or parameter declaration inside a function.
+type def struct {
+	name    string
+	node    tree_sitter.Node  // the whole let_declaration or parameter node
+	value   *tree_sitter.Node // the let's value expression; nil for a parameter
+	tainted bool
+}
+
+// function is one function_item's def-use graph: every let binding and
+// parameter declared inside it, keyed by name so a use can look its def up
+// by the identifier text it shares with it. Shadowing isn't modeled - a
+// second def with the same name simply overwrites the first in the map -
+// which is wrong for a shadowed outer variable but keeps this "lightweight"
+// as the request asks for rather than scope-tracking every block.
+type function struct {
+	name     string
+	node     tree_sitter.Node
+	defs     map[string]*def
+	defOrder []*def
+}
+
+// captures is the @source/@sink/@sanitizer nodes one compiled taint Query
+// matched against the whole file.
+type captures struct {
+	source    []tree_sitter.Node
+	sink      []tree_sitter.Node
+	sanitizer []tree_sitter.Node
+}
+
+// Run compiles each query's pattern against backend's grammar and runs the
+// taint analysis it describes over every function in tree, returning one
+// Finding per tainted value that reaches a @sink without passing through a
+// @sanitizer. A query whose pattern fails to compile is skipped - printed a
+// warning and left out of the results - rather than aborting the batch, the
+// same "one bad query doesn't sink the others" behavior RunQueries'
+// ordinary pattern-match loop already has.
+func Run(source []byte, tree *tree_sitter.Tree, backend languages.Backend, queries []Query) ([]Finding, error) {
+	lang := backend.TSLanguage()
+	root := tree.RootNode()
+
+	functions, err := collectFunctions(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, q := range queries {
+		query, err := tree_sitter.NewQuery(lang, q.Pattern)
+		if err != nil {
+			fmt.Printf("Error compiling taint query %s: %v\n", q.File, err)
+			continue
+		}
+
+		caps := collectCaptures(query, root, source)
+		query.Close()
+
+		for _, fn := range functions {
+			findings = append(findings, analyzeFunction(fn, caps, q, source)...)
+		}
+	}
+	return findings, nil
+}
+
+// collectFunctions walks root for every function_item and, for each one,
+// every let binding and parameter declared inside it, identified by byte
+// range since neither query can capture its own enclosing function.
+func collectFunctions(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]*function, error) {
+	fnNodes, err := queryFunctionNodes(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+	lets, err := queryLetDefs(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+	params, err := queryParamDefs(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+
+	functions := make([]*function, len(fnNodes))
+	for i, fnNode := range fnNodes {
+		fn := &function{
+			name: fnNode.name,
+			node: fnNode.node,
+			defs: make(map[string]*def),
+		}
+		for _, d := range lets {
+			if within(d.node, fnNode.node) {
+				fn.defs[d.name] = d
+				fn.defOrder = append(fn.defOrder, d)
+			}
+		}
+		for _, d := range params {
+			if within(d.node, fnNode.node) {
+				fn.defs[d.name] = d
+				fn.defOrder = append(fn.defOrder, d)
+			}
+		}
+		functions[i] = fn
+	}
+	return functions, nil
+}
+
+// functionNode is one function_item's own node and name.
+type functionNode struct {
+	name string
+	node tree_sitter.Node
+}
+
+func queryFunctionNodes(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]functionNode, error) {
+	query, err := tree_sitter.NewQuery(lang, functionQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling function query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var result []functionNode
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var fnNode, nameNode *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch names[capture.Index] {
+			case "fn":
+				fnNode = &node
+			case "fn_name":
+				nameNode = &node
+			}
+		}
+		if fnNode == nil || nameNode == nil {
+			continue
+		}
+		result = append(result, functionNode{
+			name: nodeText(*nameNode, source),
+			node: *fnNode,
+		})
+	}
+	return result, nil
+}
+
+func queryLetDefs(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]*def, error) {
+	query, err := tree_sitter.NewQuery(lang, letDefQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling let def query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var result []*def
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var letNode, nameNode, valueNode *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch names[capture.Index] {
+			case "let":
+				letNode = &node
+			case "name":
+				nameNode = &node
+			case "value":
+				valueNode = &node
+			}
+		}
+		if letNode == nil || nameNode == nil {
+			continue
+		}
+		result = append(result, &def{
+			name:  nodeText(*nameNode, source),
+			node:  *letNode,
+			value: valueNode,
+		})
+	}
+	return result, nil
+}
+
+func queryParamDefs(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]*def, error) {
+	query, err := tree_sitter.NewQuery(lang, paramDefQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling param def query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var result []*def
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var paramNode, nameNode *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch names[capture.Index] {
+			case "param":
+				paramNode = &node
+			case "name":
+				nameNode = &node
+			}
+		}
+		if paramNode == nil || nameNode == nil {
+			continue
+		}
+		result = append(result, &def{
+			name: nodeText(*nameNode, source),
+			node: *paramNode,
+		})
+	}
+	return result, nil
+}
+
+// collectCaptures runs query against root and buckets every @source, @sink,
+// and @sanitizer capture it matches. A query with no capture by one of
+// those names simply leaves that bucket empty - a query with only @source
+// and @sink, say, never sanitizes anything.
+func collectCaptures(query *tree_sitter.Query, root *tree_sitter.Node, source []byte) captures {
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var caps captures
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		for _, capture := range match.Captures {
+			switch names[capture.Index] {
+			case "source":
+				caps.source = append(caps.source, capture.Node)
+			case "sink":
+				caps.sink = append(caps.sink, capture.Node)
+			case "sanitizer":
+				caps.sanitizer = append(caps.sanitizer, capture.Node)
+			}
+		}
+	}
+	return caps
+}
+
+// analyzeFunction seeds fn's defs from caps.source, propagates taint
+// through them to fixpoint, and reports a Finding for every caps.sink node
+// inside fn that's still reachable from a tainted def without passing
+// through a caps.sanitizer node on the way.
+func analyzeFunction(fn *function, caps captures, q Query, source []byte) []Finding {
+	// A def is seeded from its whole declaration, so a @source on the bound
+	// name (or parameter) counts as well as one inside the let's value.
+	for _, d := range fn.defOrder {
+		d.tainted = containsUnsanitized(d.node, caps.source, caps.sanitizer)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, d := range fn.defOrder {
+			if d.tainted || d.value == nil {
+				continue
+			}
+			for _, use := range identifierRefs(*d.value, source) {
+				if withinAny(use.node, caps.sanitizer) {
+					continue
+				}
+				if ud, ok := fn.defs[use.name]; ok && ud.tainted {
+					d.tainted = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var findings []Finding
+	for _, sink := range caps.sink {
+		if !within(sink, fn.node) {
+			continue
+		}
+		if withinAny(sink, caps.sanitizer) {
+			continue
+		}
+		if !sinkIsTainted(sink, fn, caps.sanitizer, source) {
+			continue
+		}
+
+		startPosition := sink.StartPosition()
+		endPosition := sink.EndPosition()
+		findings = append(findings, Finding{
+			QueryName:          q.Name,
+			QueryFile:          q.File,
+			Description:        q.Description,
+			LineNumber:         uint32(startPosition.Row) + 1,
+			EndLine:            uint32(endPosition.Row) + 1,
+			Column:             uint32(startPosition.Column) + 1,
+			StartByte:          uint32(sink.StartByte()),
+			EndByte:            uint32(sink.EndByte()),
+			Code:               nodeText(sink, source),
+			Severity:           q.Severity,
+			EnclosingConstruct: "function_item:" + fn.name,
+			Function:           fn.name,
+		})
+	}
+	return findings
+}
+
+// sinkIsTainted reports whether sink is, or refers to, a tainted def: either
+// sink is itself the identifier a tainted def was declared with, or one of
+// the identifiers it contains is a use of one - except a use wrapped by its
+// own @sanitizer node (sink is a compound expression like "f(validate(x),
+// y)", where x is cleaned but y isn't), the same sanitized-inline case the
+// seeding step in analyzeFunction guards against. Callers also check
+// whether sink as a whole falls inside a sanitizer before calling this.
+func sinkIsTainted(sink tree_sitter.Node, fn *function, sanitizers []tree_sitter.Node, source []byte) bool {
+	if sink.Kind() == "identifier" {
+		if d, ok := fn.defs[nodeText(sink, source)]; ok && d.tainted {
+			return true
+		}
+	}
+	for _, use := range identifierRefs(sink, source) {
+		if withinAny(use.node, sanitizers) {
+			continue
+		}
+		if d, ok := fn.defs[use.name]; ok && d.tainted {
+			return true
+		}
+	}
+	return false
+}
+
+// ref is one identifier reference found inside some enclosing node.
+type ref struct {
+	name string
+	node tree_sitter.Node
+}
+
+// identifierRefs returns every identifier node inside scope, read directly
+// off the tree rather than through a compiled identifierQuery per call -
+// scope is typically a single let's value expression, small enough that a
+// manual walk is cheaper than spinning up a cursor for it.
+func identifierRefs(scope tree_sitter.Node, source []byte) []ref {
+	var refs []ref
+	var walk func(n tree_sitter.Node)
+	walk = func(n tree_sitter.Node) {
+		if n.Kind() == "identifier" {
+			refs = append(refs, ref{name: nodeText(n, source), node: n})
+		}
+		for i := uint(0); i < n.NamedChildCount(); i++ {
+			if child := n.NamedChild(i); child != nil {
+				walk(*child)
+			}
+		}
+	}
+	walk(scope)
+	return refs
+}
+
+// within reports whether inner's byte range falls inside outer's.
+func within(inner, outer tree_sitter.Node) bool {
+	return inner.StartByte() >= outer.StartByte() && inner.EndByte() <= outer.EndByte()
+}
+
+// withinAny reports whether node's byte range falls inside any of others.
+func withinAny(node tree_sitter.Node, others []tree_sitter.Node) bool {
+	for _, o := range others {
+		if within(node, o) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsUnsanitized reports whether any of sources falls inside outer's
+// byte range without also falling inside one of sanitizers - the reverse
+// test from within, used to seed a def as tainted only when its declaration
+// actually carries untrusted data through to the def, not data a sanitizer
+// already cleaned in the same expression (`validate(external_input())`
+// taints nothing, `x + external_input()` does).
+func containsUnsanitized(outer tree_sitter.Node, sources, sanitizers []tree_sitter.Node) bool {
+	for _, s := range sources {
+		if within(s, outer) && !withinAny(s, sanitizers) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeText returns node's source text.
+func nodeText(node tree_sitter.Node, source []byte) string {
+	return string(source[node.StartByte():node.EndByte()])
+}
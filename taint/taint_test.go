@@ -0,0 +1,178 @@
+package taint_test
+
+import (
+	"testing"
+
+	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/languages"
+	"github.com/jeffrydegrande/solidair/taint"
+)
+
+func cairoBackend(t *testing.T) languages.Backend {
+	t.Helper()
+	backend, ok := languages.ForExtension(".cairo")
+	if !ok {
+		t.Fatal("expected a backend registered for .cairo")
+	}
+	return backend
+}
+
+const divisorSourceAndSink = `
+(let_declaration
+  pattern: (identifier) @source
+  value: (call_expression
+    function: (identifier) @_fn
+    (#eq? @_fn "external_input")))
+
+(binary_expression
+  right: (identifier) @sink)
+`
+
+func TestRunFindsTaintedValueReachingSink(t *testing.T) {
+	source := []byte(`
+		func main() {
+			let divisor = external_input();
+			let result = 100 / divisor;
+		}
+	`)
+	tree, err := cairo.Parse(source)
+	if err != nil {
+		t.Fatalf("cairo.Parse() error = %v", err)
+	}
+	defer tree.Close()
+
+	findings, err := taint.Run(source, tree, cairoBackend(t), []taint.Query{
+		{Name: "unchecked-divisor", File: "unchecked-divisor.scm", Pattern: divisorSourceAndSink},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d: %+v", len(findings), findings)
+	}
+	if findings[0].Function != "main" {
+		t.Errorf("Function = %q, want %q", findings[0].Function, "main")
+	}
+	if findings[0].Code != "divisor" {
+		t.Errorf("Code = %q, want %q", findings[0].Code, "divisor")
+	}
+}
+
+func TestRunIgnoresUntaintedValueReachingSink(t *testing.T) {
+	source := []byte(`
+		func main() {
+			let divisor = 2;
+			let result = 100 / divisor;
+		}
+	`)
+	tree, err := cairo.Parse(source)
+	if err != nil {
+		t.Fatalf("cairo.Parse() error = %v", err)
+	}
+	defer tree.Close()
+
+	findings, err := taint.Run(source, tree, cairoBackend(t), []taint.Query{
+		{Name: "unchecked-divisor", File: "unchecked-divisor.scm", Pattern: divisorSourceAndSink},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(findings) != 0 {
+		t.Errorf("expected no findings for a constant divisor, got %+v", findings)
+	}
+}
+
+const divisorWithSanitizer = `
+(let_declaration
+  pattern: (identifier) @source
+  value: (call_expression
+    function: (identifier) @_fn
+    (#eq? @_fn "external_input")))
+
+(call_expression
+  function: (identifier) @_validate
+  (#eq? @_validate "validate")) @sanitizer
+
+(binary_expression
+  right: (identifier) @sink)
+`
+
+func TestRunTreatsSanitizedValueAsClean(t *testing.T) {
+	source := []byte(`
+		func main() {
+			let divisor = validate(external_input());
+			let result = 100 / divisor;
+		}
+	`)
+	tree, err := cairo.Parse(source)
+	if err != nil {
+		t.Fatalf("cairo.Parse() error = %v", err)
+	}
+	defer tree.Close()
+
+	findings, err := taint.Run(source, tree, cairoBackend(t), []taint.Query{
+		{Name: "unchecked-divisor", File: "unchecked-divisor.scm", Pattern: divisorWithSanitizer},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(findings) != 0 {
+		t.Errorf("expected the validate() call to sanitize the source, got %+v", findings)
+	}
+}
+
+func TestRunPropagatesTaintThroughIntermediateLet(t *testing.T) {
+	source := []byte(`
+		func main() {
+			let divisor = external_input();
+			let copy = divisor;
+			let result = 100 / copy;
+		}
+	`)
+	tree, err := cairo.Parse(source)
+	if err != nil {
+		t.Fatalf("cairo.Parse() error = %v", err)
+	}
+	defer tree.Close()
+
+	findings, err := taint.Run(source, tree, cairoBackend(t), []taint.Query{
+		{Name: "unchecked-divisor", File: "unchecked-divisor.scm", Pattern: divisorSourceAndSink},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(findings) != 1 {
+		t.Fatalf("expected taint to propagate through an intermediate let binding, got %d findings: %+v", len(findings), findings)
+	}
+	if findings[0].Code != "copy" {
+		t.Errorf("Code = %q, want %q", findings[0].Code, "copy")
+	}
+}
+
+func TestRunSkipsQueryWithInvalidPattern(t *testing.T) {
+	source := []byte(`
+		func main() {
+			let divisor = external_input();
+			let result = 100 / divisor;
+		}
+	`)
+	tree, err := cairo.Parse(source)
+	if err != nil {
+		t.Fatalf("cairo.Parse() error = %v", err)
+	}
+	defer tree.Close()
+
+	findings, err := taint.Run(source, tree, cairoBackend(t), []taint.Query{
+		{Name: "broken", File: "broken.scm", Pattern: "(not_a_real_node"},
+	})
+	if err != nil {
+		t.Fatalf("Run() error = %v, want a skipped query rather than an error", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("expected no findings from a query that fails to compile, got %+v", findings)
+	}
+}
@@ -1,22 +1,86 @@
 package types
 
+import "fmt"
+
 // Embedding represents a vector embedding for a variable or concept
 type Embedding struct {
-	Vector []float32 `toml:"vector"` // The embedding vector
+	Vector  []float32 `toml:"vector"`             // The embedding vector
+	ModelID string    `toml:"model_id,omitempty"` // Identifies the backend that produced this vector, so caches from different models never get compared
 }
 
 // SecurityConcept represents a security-related concept with its embedding
 type SecurityConcept struct {
-	Name        string    `toml:"name"`        // Concept name (e.g., "active", "initialized")
-	Description string    `toml:"description"` // Description of what this concept represents
-	Synonyms    []string  `toml:"synonyms"`    // Synonyms for this concept
-	Embedding   Embedding `toml:"embedding"`   // Pre-computed embedding for this concept
+	Name        string   `toml:"name"`        // Concept name (e.g., "active", "initialized")
+	Description string   `toml:"description"` // Description of what this concept represents
+	Synonyms    []string `toml:"synonyms"`    // Synonyms for this concept
+
+	// Severity, CWE, and References are optional metadata a concept pack can
+	// attach to its concepts (e.g. "high", "CWE-841", a links list), carried
+	// through to findings but never required by matching itself.
+	Severity   string   `toml:"severity,omitempty"`
+	CWE        string   `toml:"cwe,omitempty"`
+	References []string `toml:"references,omitempty"`
+
+	// Pack is the name of the concept pack this concept was loaded from
+	// (builtin concepts leave this empty), stamped by the loader rather than
+	// read from the pack file itself - see pkg/concepts.LoadConceptPacks.
+	Pack string `toml:"-"`
+
+	// Embedding is this concept's default vector: the one MatchVariable
+	// compares against when it doesn't need to pick among several. It's
+	// PreferredEmbedder's vector when that's set and present in Embeddings,
+	// otherwise the first embedder loaded for this concept.
+	Embedding Embedding `toml:"embedding"`
+
+	// Embeddings holds this concept's vectors from every embedder that has
+	// produced one, keyed by embedder ID (EmbeddingProvider.ID(), e.g.
+	// "openai:text-embedding-3-small"), so embeddings.toml can carry more
+	// than one embedder's output for the same concept side by side.
+	// Embedding is always also reachable through this map under its own ID.
+	Embeddings map[string]Embedding `toml:"embeddings,omitempty"`
+
+	// PreferredEmbedder, if set, names the embedder ID in Embeddings whose
+	// vector should become Embedding when concepts are loaded. Leave empty
+	// to use whichever embedder's vector was loaded first.
+	PreferredEmbedder string `toml:"preferred_embedder,omitempty"`
+
+	// SemanticRatio overrides EmbeddingMatcher.SemanticRatio for matches
+	// against this concept in Hybrid mode, e.g. to lean more on lexical
+	// matching for a concept whose embedding is known to rank poorly. Nil
+	// means use the matcher's default.
+	SemanticRatio *float32 `toml:"semantic_ratio,omitempty"`
+}
+
+// EmbeddingFor returns this concept's vector produced by the embedder
+// identified by modelID, if one was loaded - either Embedding itself or one
+// of the side-by-side vectors in Embeddings. Callers use this instead of
+// Embedding directly to make sure they never compare a variable's vector
+// against a concept vector from a different embedding space.
+func (c SecurityConcept) EmbeddingFor(modelID string) (Embedding, bool) {
+	if c.Embedding.ModelID == modelID {
+		return c.Embedding, true
+	}
+	if e, ok := c.Embeddings[modelID]; ok {
+		return e, true
+	}
+	return Embedding{}, false
 }
 
-// EmbeddingEntry stores an embedding with its concept name for easier mapping
+// EmbeddingEntry stores an embedding with its concept name for easier
+// mapping. Several entries may share a ConceptName as long as their
+// EmbedderName (or, absent that, their Embedding.ModelID) differs, so
+// embeddings.toml can hold one concept's vectors from several embedders.
 type EmbeddingEntry struct {
-	ConceptName string    `toml:"concept_name"`
-	Embedding   Embedding `toml:"embedding"`
+	ConceptName string `toml:"concept_name"`
+	// EmbedderName is the embedders.toml entry that produced this vector,
+	// e.g. "openai-small" or "local-ollama". Falls back to
+	// Embedding.ModelID when empty, for files written before this field
+	// existed.
+	EmbedderName string `toml:"embedder_name,omitempty"`
+	// Dimensions is len(Embedding.Vector), recorded alongside the vector so
+	// tooling can sanity-check a file without decoding every entry.
+	Dimensions int       `toml:"dimensions,omitempty"`
+	Embedding  Embedding `toml:"embedding"`
 }
 
 // VariableInfo represents information about an extracted variable
@@ -29,9 +93,42 @@ type VariableInfo struct {
 	Comments   []string // Associated comments
 }
 
+// Prompt is the text EmbeddingMatcher embeds for v, used instead of its bare
+// Name so the embedding reflects what kind of declaration it is and not
+// just an identifier that could mean anything out of context - "storage
+// variable Storage.is_paused: bool" matches a concept like "paused" far
+// more precisely than "is_paused" alone would.
+func (v VariableInfo) Prompt() string {
+	label := v.Context
+	if label == "" {
+		label = "variable"
+	}
+
+	name := v.Name
+	if v.ParentName != "" {
+		name = v.ParentName + "." + v.Name
+	}
+
+	if v.Type != "" {
+		return fmt.Sprintf("%s %s: %s", label, name, v.Type)
+	}
+	return fmt.Sprintf("%s %s", label, name)
+}
+
 // ConceptMatch represents a match between a variable and a security concept
 type ConceptMatch struct {
 	Variable        VariableInfo // The matched variable
 	Concept         string       // The security concept (e.g., "active", "initialized")
 	SimilarityScore float32      // 0.0-1.0 score of the match quality
-}
\ No newline at end of file
+}
+
+// ChunkMatch represents a match between a chunk of source code and a
+// security concept, so queries can be gated on code that is semantically
+// about a concept (locking, reentrancy) rather than only on variable names.
+type ChunkMatch struct {
+	FilePath        string  // Source file the chunk came from
+	StartLine       uint32  // First line of the chunk (1-based)
+	EndLine         uint32  // Last line of the chunk (1-based)
+	Concept         string  // The security concept this chunk matched
+	SimilarityScore float32 // 0.0-1.0 score of the match quality
+}
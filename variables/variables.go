@@ -3,9 +3,8 @@ package variables
 import (
 	"fmt"
 	"strings"
-	"unsafe"
 
-	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/languages"
 	"github.com/jeffrydegrande/solidair/types"
 	tree_sitter "github.com/tree-sitter/go-tree-sitter"
 )
@@ -16,53 +15,315 @@ type ExtractedVariables struct {
 	Variables []types.VariableInfo // All extracted variables
 }
 
-// ExtractVariables extracts variables from Cairo source code using Tree-sitter
-func ExtractVariables(source []byte, tree *tree_sitter.Tree) (*ExtractedVariables, error) {
+// storageFieldQuery captures each field of the contract's Storage struct,
+// the fields EmbeddingMatcher most wants to match against security concepts
+// like "active" or "paused".
+const storageFieldQuery = `
+(struct_item
+  name: (identifier) @struct_name
+  body: (field_declaration_list
+    (field_declaration
+      name: (identifier) @field_name
+      type: (_) @field_type)))
+`
+
+// parameterQuery captures every function's parameters, along with the
+// function's own name so ParentName can record what the parameter belongs
+// to.
+const parameterQuery = `
+(function_item
+  name: (identifier) @fn_name
+  parameters: (parameters
+    (parameter
+      pattern: (identifier) @param_name
+      type: (_) @param_type)))
+`
+
+// letBindingQuery captures every let/let-mut binding's name and the let
+// node itself, off which extractLocals reads the declared type where
+// present. The enclosing function isn't capturable in
+// the query itself (a let can nest arbitrarily deep inside blocks), so
+// callers walk up via Node.Parent() to find it.
+const letBindingQuery = `
+(let_declaration
+  pattern: (identifier) @name) @let
+`
+
+// constItemQuery captures top-level and nested const declarations.
+const constItemQuery = `
+(const_item
+  name: (identifier) @name
+  type: (_) @type) @const
+`
+
+// ExtractVariables extracts variables from Cairo source code using
+// Tree-sitter, tagging each with the Cairo construct it came from - a
+// Storage struct field, a function parameter, a let binding, or a const -
+// so EmbeddingMatcher can build a context-aware embedding prompt instead of
+// a bare identifier. The same name can appear more than once if it's used
+// in more than one context (a storage field and an unrelated local sharing
+// a name, say): entries are never deduped across - or even within - a
+// context, since each occurrence is a distinct declaration a reviewer might
+// care about individually.
+//
+// The queries above are written against Cairo's own grammar (struct_item,
+// function_item, let_declaration), so this only extracts anything
+// meaningful when backend is the Cairo backend; it's still parameterized on
+// languages.Backend rather than hardcoding that, so ExtractVariables compiles
+// its queries against whatever backend.TSLanguage() the caller resolved a
+// file to, instead of this package importing cairo directly.
+func ExtractVariables(source []byte, tree *tree_sitter.Tree, backend languages.Backend) (*ExtractedVariables, error) {
 	vars := &ExtractedVariables{
 		Variables: make([]types.VariableInfo, 0),
 	}
 
-	// Simple query to find all identifiers
-	query := "(identifier) @id"
+	lang := backend.TSLanguage()
+	root := tree.RootNode()
+
+	storage, err := extractStorageFields(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+	vars.Variables = append(vars.Variables, storage...)
+
+	params, err := extractParameters(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+	vars.Variables = append(vars.Variables, params...)
+
+	locals, err := extractLocals(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+	vars.Variables = append(vars.Variables, locals...)
+
+	constants, err := extractConstants(lang, root, source)
+	if err != nil {
+		return nil, err
+	}
+	vars.Variables = append(vars.Variables, constants...)
+
+	return vars, nil
+}
 
-	lang := tree_sitter.NewLanguage(unsafe.Pointer(cairo.Language()))
-	q, err := tree_sitter.NewQuery(lang, query)
+// extractStorageFields runs storageFieldQuery against root and returns one
+// VariableInfo per field of a struct named "Storage", the only struct this
+// tool treats as a contract's storage layout.
+func extractStorageFields(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]types.VariableInfo, error) {
+	query, err := tree_sitter.NewQuery(lang, storageFieldQuery)
 	if err != nil {
-		return nil, fmt.Errorf("error compiling query: %w", err)
+		return nil, fmt.Errorf("error compiling storage field query: %w", err)
 	}
-	defer q.Close()
+	defer query.Close()
+	names := query.CaptureNames()
 
 	qc := tree_sitter.NewQueryCursor()
 	defer qc.Close()
-	matches := qc.Matches(q, tree.RootNode(), source)
+	matches := qc.Matches(query, root, source)
 
-	// Track seen variables to avoid duplicates
-	seen := make(map[string]bool)
+	var result []types.VariableInfo
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var structName, fieldName, fieldType *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch names[capture.Index] {
+			case "struct_name":
+				structName = &node
+			case "field_name":
+				fieldName = &node
+			case "field_type":
+				fieldType = &node
+			}
+		}
+		if fieldName == nil || structName == nil {
+			continue
+		}
+		if nodeText(*structName, source) != "Storage" {
+			continue
+		}
 
-	// Process the matches
+		result = append(result, types.VariableInfo{
+			Name:       nodeText(*fieldName, source),
+			Type:       nodeTextOrEmpty(fieldType, source),
+			Context:    "storage",
+			ParentName: "Storage",
+			LineNumber: uint32(fieldName.StartPosition().Row) + 1,
+			Comments:   precedingComments(*fieldName, source),
+		})
+	}
+	return result, nil
+}
+
+// extractParameters runs parameterQuery against root and returns one
+// VariableInfo per function parameter, tagged with its enclosing function's
+// name as ParentName.
+func extractParameters(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]types.VariableInfo, error) {
+	query, err := tree_sitter.NewQuery(lang, parameterQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling parameter query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var result []types.VariableInfo
 	for match := matches.Next(); match != nil; match = matches.Next() {
+		var fnName, paramName, paramType *tree_sitter.Node
 		for _, capture := range match.Captures {
 			node := capture.Node
-			text := string(source[node.StartByte():node.EndByte()])
+			switch names[capture.Index] {
+			case "fn_name":
+				fnName = &node
+			case "param_name":
+				paramName = &node
+			case "param_type":
+				paramType = &node
+			}
+		}
+		if paramName == nil {
+			continue
+		}
+
+		result = append(result, types.VariableInfo{
+			Name:       nodeText(*paramName, source),
+			Type:       nodeTextOrEmpty(paramType, source),
+			Context:    "parameter",
+			ParentName: nodeTextOrEmpty(fnName, source),
+			LineNumber: uint32(paramName.StartPosition().Row) + 1,
+			Comments:   precedingComments(*paramName, source),
+		})
+	}
+	return result, nil
+}
+
+// extractLocals runs letBindingQuery against root and returns one
+// VariableInfo per let/let-mut binding, tagged with the name of the
+// function it's declared in (found by walking up the tree, since a let can
+// nest arbitrarily deep inside blocks, ifs, and loops).
+func extractLocals(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]types.VariableInfo, error) {
+	query, err := tree_sitter.NewQuery(lang, letBindingQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling let binding query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
 
-			// Skip if we've already seen this variable
-			if seen[text] {
-				continue
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var result []types.VariableInfo
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var letNode, nameNode *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch names[capture.Index] {
+			case "let":
+				letNode = &node
+			case "name":
+				nameNode = &node
 			}
-			seen[text] = true
+		}
+		if nameNode == nil || letNode == nil {
+			continue
+		}
+
+		result = append(result, types.VariableInfo{
+			Name:       nodeText(*nameNode, source),
+			Type:       nodeTextOrEmpty(letNode.ChildByFieldName("type"), source),
+			Context:    "local",
+			ParentName: enclosingFunctionName(*letNode, source),
+			LineNumber: uint32(nameNode.StartPosition().Row) + 1,
+			Comments:   precedingComments(*letNode, source),
+		})
+	}
+	return result, nil
+}
 
-			// Simple implementation to extract variables without complex context detection
-			varInfo := types.VariableInfo{
-				Name:       text,
-				Context:    "variable", // Simplified context
-				LineNumber: uint32(node.StartPosition().Row) + 1,
+// extractConstants runs constItemQuery against root and returns one
+// VariableInfo per const declaration.
+func extractConstants(lang *tree_sitter.Language, root *tree_sitter.Node, source []byte) ([]types.VariableInfo, error) {
+	query, err := tree_sitter.NewQuery(lang, constItemQuery)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling const item query: %w", err)
+	}
+	defer query.Close()
+	names := query.CaptureNames()
+
+	qc := tree_sitter.NewQueryCursor()
+	defer qc.Close()
+	matches := qc.Matches(query, root, source)
+
+	var result []types.VariableInfo
+	for match := matches.Next(); match != nil; match = matches.Next() {
+		var constNode, nameNode, typeNode *tree_sitter.Node
+		for _, capture := range match.Captures {
+			node := capture.Node
+			switch names[capture.Index] {
+			case "const":
+				constNode = &node
+			case "name":
+				nameNode = &node
+			case "type":
+				typeNode = &node
 			}
+		}
+		if nameNode == nil || constNode == nil {
+			continue
+		}
+
+		result = append(result, types.VariableInfo{
+			Name:       nodeText(*nameNode, source),
+			Type:       nodeTextOrEmpty(typeNode, source),
+			Context:    "constant",
+			ParentName: enclosingFunctionName(*constNode, source),
+			LineNumber: uint32(nameNode.StartPosition().Row) + 1,
+			Comments:   precedingComments(*constNode, source),
+		})
+	}
+	return result, nil
+}
 
-			vars.Variables = append(vars.Variables, varInfo)
+// enclosingFunctionName walks up from node through its ancestors looking
+// for the nearest function_item, returning its name - or "" if node isn't
+// nested inside one (a top-level const, say).
+func enclosingFunctionName(node tree_sitter.Node, source []byte) string {
+	for parent := node.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.Kind() == "function_item" {
+			return nodeTextOrEmpty(parent.ChildByFieldName("name"), source)
 		}
 	}
+	return ""
+}
 
-	return vars, nil
+// precedingComments walks node's preceding siblings, collecting the text of
+// every consecutive //-or-///-style comment node directly above it, in
+// source order, so a declaration's doc comment travels with it into
+// VariableInfo.Comments.
+func precedingComments(node tree_sitter.Node, source []byte) []string {
+	var comments []string
+	for sibling := node.PrevNamedSibling(); sibling != nil && strings.Contains(sibling.Kind(), "comment"); sibling = sibling.PrevNamedSibling() {
+		comments = append([]string{nodeText(*sibling, source)}, comments...)
+	}
+	return comments
+}
+
+// nodeText returns node's source text.
+func nodeText(node tree_sitter.Node, source []byte) string {
+	return string(source[node.StartByte():node.EndByte()])
+}
+
+// nodeTextOrEmpty returns node's source text, or "" if node is nil - for
+// optional captures like a let binding's type, which isn't always present.
+func nodeTextOrEmpty(node *tree_sitter.Node, source []byte) string {
+	if node == nil {
+		return ""
+	}
+	return nodeText(*node, source)
 }
 
 // PrintExtractedVariables prints information about extracted variables
@@ -92,4 +353,3 @@ func PrintExtractedVariables(vars *ExtractedVariables) {
 		}
 	}
 }
-
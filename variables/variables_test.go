@@ -6,10 +6,20 @@ import (
 	"testing"
 
 	"github.com/jeffrydegrande/solidair/cairo"
+	"github.com/jeffrydegrande/solidair/languages"
 	"github.com/jeffrydegrande/solidair/types"
 	"github.com/jeffrydegrande/solidair/variables"
 )
 
+func cairoBackend(t *testing.T) languages.Backend {
+	t.Helper()
+	backend, ok := languages.ForExtension(".cairo")
+	if !ok {
+		t.Fatal("expected a backend registered for .cairo")
+	}
+	return backend
+}
+
 func TestExtractVariables(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -70,29 +80,29 @@ func TestExtractVariables(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to parse source: %v", err)
 			}
-			
+
 			// Extract variables
-			vars, err := variables.ExtractVariables(tt.source, tree)
+			vars, err := variables.ExtractVariables(tt.source, tree, cairoBackend(t))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ExtractVariables() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
-			
+
 			if err != nil {
 				return
 			}
-			
+
 			// Verify the extracted variables
 			if vars == nil {
 				t.Errorf("ExtractVariables() returned nil")
 				return
 			}
-			
+
 			// Check if we have variables
 			if len(tt.source) > 0 && len(vars.Variables) == 0 {
 				t.Errorf("ExtractVariables() returned no variables for non-empty source")
 			}
-			
+
 			// Check if expected variable is present
 			if tt.wantVarName != "" {
 				var found bool
@@ -102,7 +112,7 @@ func TestExtractVariables(t *testing.T) {
 						break
 					}
 				}
-				
+
 				if !found {
 					t.Errorf("ExtractVariables() didn't extract expected variable %s", tt.wantVarName)
 				}
@@ -117,7 +127,7 @@ func TestExtractVariablesFromFile(t *testing.T) {
 		"/home/jeffry/Code/Olympix/assignment/examples/good.cairo",
 		"/home/jeffry/Code/Olympix/assignment/examples/bad.cairo",
 	}
-	
+
 	for _, file := range files {
 		t.Run(file, func(t *testing.T) {
 			// Read the file
@@ -125,19 +135,19 @@ func TestExtractVariablesFromFile(t *testing.T) {
 			if err != nil {
 				t.Fatalf("Failed to read file %s: %v", file, err)
 			}
-			
+
 			// Parse the source
 			tree, err := cairo.Parse(content)
 			if err != nil {
 				t.Fatalf("Failed to parse source: %v", err)
 			}
-			
+
 			// Extract variables
-			vars, err := variables.ExtractVariables(content, tree)
+			vars, err := variables.ExtractVariables(content, tree, cairoBackend(t))
 			if err != nil {
 				t.Fatalf("ExtractVariables() error = %v", err)
 			}
-			
+
 			// Verify we extracted variables
 			if len(vars.Variables) == 0 {
 				t.Errorf("ExtractVariables() returned no variables for file %s", file)
@@ -165,33 +175,33 @@ func TestPrintExtractedVariables(t *testing.T) {
 			},
 		},
 	}
-	
+
 	// Redirect stdout to capture printed output
 	oldStdout := os.Stdout
 	r, w, _ := os.Pipe()
 	os.Stdout = w
-	
+
 	// Call the function
 	variables.PrintExtractedVariables(vars)
-	
+
 	// Restore stdout
 	w.Close()
 	os.Stdout = oldStdout
-	
+
 	// Read captured output
 	var buf bytes.Buffer
 	buf.ReadFrom(r)
 	output := buf.String()
-	
+
 	// Verify output contains expected info
 	if !contains(output, "Extracted 2 variables") {
 		t.Errorf("PrintExtractedVariables() output doesn't contain variable count")
 	}
-	
+
 	if !contains(output, "test_var") || !contains(output, "another_var") {
 		t.Errorf("PrintExtractedVariables() output doesn't contain variable names")
 	}
-	
+
 	if !contains(output, "bool") || !contains(output, "felt252") {
 		t.Errorf("PrintExtractedVariables() output doesn't contain variable types")
 	}
@@ -200,4 +210,4 @@ func TestPrintExtractedVariables(t *testing.T) {
 // Helper function to check if string contains substring
 func contains(s, substr string) bool {
 	return bytes.Contains([]byte(s), []byte(substr))
-}
\ No newline at end of file
+}